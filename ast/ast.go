@@ -4,6 +4,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strings"
 
 	"monkey-interpreter/token"
@@ -75,6 +76,45 @@ func (ls *LetStatement) String() string {
 	return buf.String()
 }
 
+// DestructuringStatement binds each of Names to the element at the same
+// position in the array Value evaluates to, e.g. `let [a, b] = pair;`. If
+// Hash is set, each name is bound to the value under the string key of the
+// same name instead, e.g. `let {name, age} = person;`
+type DestructuringStatement struct {
+	Token token.Token // the token.LET token
+	Names []*Identifier
+	Hash  bool
+	Value Expression
+}
+
+func (ds *DestructuringStatement) statementNode()       {}
+func (ds *DestructuringStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DestructuringStatement) String() string {
+	buf := bytes.Buffer{}
+
+	names := []string{}
+	for _, name := range ds.Names {
+		names = append(names, name.Value)
+	}
+
+	opening, closing := "[", "]"
+	if ds.Hash {
+		opening, closing = "{", "}"
+	}
+
+	buf.WriteString(ds.TokenLiteral() + " " + opening)
+	buf.WriteString(strings.Join(names, ", "))
+	buf.WriteString(closing + " = ")
+
+	if ds.Value != nil {
+		buf.WriteString(ds.Value.String())
+	}
+
+	buf.WriteString(";")
+
+	return buf.String()
+}
+
 type ReturnStatement struct {
 	Token       token.Token // the token.RETURN token
 	ReturnValue Expression
@@ -86,10 +126,10 @@ func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string {
 	buf := bytes.Buffer{}
 
-	buf.WriteString(rs.TokenLiteral() + " ")
+	buf.WriteString(rs.TokenLiteral())
 
 	if rs.ReturnValue != nil {
-		buf.WriteString(rs.ReturnValue.String())
+		buf.WriteString(" " + rs.ReturnValue.String())
 	}
 
 	buf.WriteString(";")
@@ -124,6 +164,36 @@ func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+// BigIntegerLiteral is an integer literal too large for an IntegerLiteral,
+// which the parser only produces when asked to
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode() {}
+func (bl *BigIntegerLiteral) TokenLiteral() string {
+	return bl.Token.Literal
+}
+
+func (bl *BigIntegerLiteral) String() string {
+	return bl.Token.Literal
+}
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -166,6 +236,28 @@ func (ie *InfixExpression) String() string {
 	return buf.String()
 }
 
+// ComparisonChain is a run of relational operators like 1 < x < 10, which
+// means 1 < x && x < 10 with x evaluated once
+type ComparisonChain struct {
+	Token     token.Token // the first operator token
+	Operands  []Expression
+	Operators []string // Operators[i] sits between Operands[i] and Operands[i+1]
+}
+
+func (cc *ComparisonChain) expressionNode()      {}
+func (cc *ComparisonChain) TokenLiteral() string { return cc.Token.Literal }
+func (cc *ComparisonChain) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("(")
+	buf.WriteString(cc.Operands[0].String())
+	for i, op := range cc.Operators {
+		buf.WriteString(" " + op + " ")
+		buf.WriteString(cc.Operands[i+1].String())
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
 type BooleanExpression struct {
 	Token token.Token
 	Value bool
@@ -180,6 +272,19 @@ func (b *BooleanExpression) String() string {
 	return b.Token.Literal
 }
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode() {}
+func (n *NullLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
 type IfExpression struct {
 	Token       token.Token // The "if" token
 	Condition   Expression
@@ -203,6 +308,78 @@ func (ie *IfExpression) String() string {
 	return buf.String()
 }
 
+type TernaryExpression struct {
+	Token       token.Token // The "?" token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (te *TernaryExpression) expressionNode()      {}
+func (te *TernaryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TernaryExpression) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("(")
+	buf.WriteString(te.Condition.String())
+	buf.WriteString(" ? ")
+	buf.WriteString(te.Consequence.String())
+	buf.WriteString(" : ")
+	buf.WriteString(te.Alternative.String())
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// DoExpression runs its body in a new scope and evaluates to the value of
+// the last statement
+type DoExpression struct {
+	Token token.Token // The "do" token
+	Body  *BlockStatement
+}
+
+func (de *DoExpression) expressionNode()      {}
+func (de *DoExpression) TokenLiteral() string { return de.Token.Literal }
+func (de *DoExpression) String() string {
+	return "do " + de.Body.String()
+}
+
+// MatchExpression compares Subject against the pattern of each arm in turn
+// and evaluates to the value of the first one that matches
+type MatchExpression struct {
+	Token   token.Token // The "match" token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// MatchArm pairs a literal pattern, or the wildcard `_`, with a value
+type MatchArm struct {
+	Pattern Expression
+	Value   Expression
+}
+
+// IsWildcard reports whether the arm matches any value
+func (ma *MatchArm) IsWildcard() bool {
+	ident, ok := ma.Pattern.(*Identifier)
+	return ok && ident.Value == "_"
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var buf bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.Pattern.String()+" => "+arm.Value.String())
+	}
+
+	buf.WriteString("match ")
+	buf.WriteString(me.Subject.String())
+	buf.WriteString(" { ")
+	buf.WriteString(strings.Join(arms, ", "))
+	buf.WriteString(" }")
+	return buf.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token // The "{" token
 	Statements []Statement
@@ -221,9 +398,74 @@ func (bs *BlockStatement) String() string {
 	return buf.String()
 }
 
+type ForStatement struct {
+	Token     token.Token // The "for" token
+	Init      Statement
+	Condition Expression
+	Post      Statement
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("for (")
+	if fs.Init != nil {
+		buf.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	buf.WriteString("; ")
+	if fs.Condition != nil {
+		buf.WriteString(fs.Condition.String())
+	}
+	buf.WriteString("; ")
+	if fs.Post != nil {
+		buf.WriteString(fs.Post.String())
+	}
+	buf.WriteString(") ")
+	buf.WriteString(fs.Body.String())
+	return buf.String()
+}
+
+type WhileStatement struct {
+	Token     token.Token // The "while" token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("while")
+	buf.WriteString(ws.Condition.String())
+	buf.WriteString(" ")
+	buf.WriteString(ws.Body.String())
+	return buf.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // The "break" token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+type ContinueStatement struct {
+	Token token.Token // The "continue" token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
 type FunctionLiteral struct {
 	Token      token.Token // The "fn" token
+	Name       string      // empty for anonymous functions
 	Parameters []*Identifier
+	Defaults   []Expression // parallel to Parameters, nil for required ones
+	Rest       *Identifier  // collects extra arguments, nil if not variadic
 	Body       *BlockStatement
 }
 
@@ -235,10 +477,20 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 func (fl *FunctionLiteral) String() string {
 	var buf bytes.Buffer
 	params := []string{}
-	for _, param := range fl.Parameters {
+	for i, param := range fl.Parameters {
+		if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+			params = append(params, param.TokenLiteral()+" = "+fl.Defaults[i].String())
+			continue
+		}
 		params = append(params, param.TokenLiteral())
 	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.TokenLiteral())
+	}
 	buf.WriteString(fl.TokenLiteral())
+	if fl.Name != "" {
+		buf.WriteString(" " + fl.Name)
+	}
 	buf.WriteString("(")
 	buf.WriteString(strings.Join(params, ", "))
 	buf.WriteString(")")
@@ -289,6 +541,32 @@ func (s *StringLiteral) String() string {
 	return buf.String()
 }
 
+// InterpolatedString is a string with ${...} expressions in it. Literal
+// segments are kept in Parts as StringLiterals
+type InterpolatedString struct {
+	Token token.Token // The String token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	buf := bytes.Buffer{}
+	buf.WriteByte('"')
+	for _, part := range is.Parts {
+		if s, ok := part.(*StringLiteral); ok {
+			buf.WriteString(strings.ReplaceAll(s.Value, "${", "\\${"))
+			continue
+		}
+		buf.WriteString("${")
+		buf.WriteString(part.String())
+		buf.WriteString("}")
+	}
+	buf.WriteByte('"')
+
+	return buf.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token // the "[" token
 	Elements []Expression
@@ -325,9 +603,90 @@ func (ie *IndexExpression) String() string {
 	return buf.String()
 }
 
+// SliceExpression is a[low:high]. Either bound can be left out, making it
+// nil
+type SliceExpression struct {
+	Token token.Token // the "[" token
+	Left  Expression
+	Low   Expression
+	High  Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	buf := bytes.Buffer{}
+	buf.WriteString(se.Left.String())
+	buf.WriteString("([")
+	if se.Low != nil {
+		buf.WriteString(se.Low.String())
+	}
+	buf.WriteString(":")
+	if se.High != nil {
+		buf.WriteString(se.High.String())
+	}
+	buf.WriteString("])")
+	return buf.String()
+}
+
+// PostfixExpression is an increment or decrement like i++ or a[0]--
+type PostfixExpression struct {
+	Token    token.Token // the "++" or "--" token
+	Target   Expression
+	Operator string
+}
+
+func (pe *PostfixExpression) expressionNode()      {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	return "(" + pe.Target.String() + pe.Operator + ")"
+}
+
+type AssignExpression struct {
+	Token  token.Token // the "=" token
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	buf := bytes.Buffer{}
+	buf.WriteString("(")
+	buf.WriteString(ae.Target.String())
+	buf.WriteString(" = ")
+	buf.WriteString(ae.Value.String())
+	buf.WriteString(")")
+	return buf.String()
+}
+
 type HashLiteral struct {
 	Token token.Token // the "{" token
 	Pairs map[Expression]Expression
+	Keys  []Expression // the keys of Pairs in source order
+}
+
+// HashLiteralPair is a key of a HashLiteral with its value
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
+}
+
+// OrderedPairs returns the pairs in source order. Hash literals built
+// outside the parser may only fill in Pairs, in which case the pairs come
+// in no particular order
+func (hl *HashLiteral) OrderedPairs() []HashLiteralPair {
+	pairs := make([]HashLiteralPair, 0, len(hl.Pairs))
+	if len(hl.Keys) == 0 {
+		for key, value := range hl.Pairs {
+			pairs = append(pairs, HashLiteralPair{Key: key, Value: value})
+		}
+		return pairs
+	}
+	for _, key := range hl.Keys {
+		pairs = append(pairs, HashLiteralPair{Key: key, Value: hl.Pairs[key]})
+	}
+	return pairs
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -335,11 +694,36 @@ func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
 	buf := bytes.Buffer{}
 	pairs := []string{}
-	for key, val := range hl.Pairs {
-		pairs = append(pairs, strings.Join([]string{key.String(), val.String()}, ":"))
+	for _, pair := range hl.OrderedPairs() {
+		pairs = append(pairs, strings.Join([]string{pair.Key.String(), pair.Value.String()}, ":"))
 	}
 	buf.WriteString("{\n")
 	buf.WriteString(strings.Join(pairs, ","))
 	buf.WriteString("\n}")
 	return buf.String()
 }
+
+type MacroLiteral struct {
+	Token      token.Token // the "macro" token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var buf bytes.Buffer
+	params := []string{}
+	for _, param := range ml.Parameters {
+		params = append(params, param.String())
+	}
+	buf.WriteString(ml.TokenLiteral())
+	buf.WriteString("(")
+	buf.WriteString(strings.Join(params, ", "))
+	buf.WriteString(")")
+	buf.WriteString("{")
+	buf.WriteString(ml.Body.String())
+	buf.WriteString("}")
+
+	return buf.String()
+}
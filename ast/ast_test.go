@@ -36,3 +36,20 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestHashLiteralOrderedPairs(t *testing.T) {
+	key, value := &StringLiteral{Value: "a"}, &IntegerLiteral{Value: 1}
+	key.Token = token.Token{Type: token.STRING, Literal: "a"}
+	value.Token = token.Token{Type: token.INT, Literal: "1"}
+
+	// Hash literals built without the parser may leave Keys empty
+	hash := &HashLiteral{Pairs: map[Expression]Expression{key: value}}
+
+	pairs := hash.OrderedPairs()
+	if len(pairs) != 1 || pairs[0].Key != key || pairs[0].Value != value {
+		t.Errorf("OrderedPairs wrong. got=%+v", pairs)
+	}
+	if hash.String() != "{\n\"a\":1\n}" {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
@@ -0,0 +1,225 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToJSON serializes the tree rooted at node. Every node becomes an object
+// with a "kind" field naming its type, alongside its fields and children.
+// Missing optional children are encoded as null
+func ToJSON(node Node) ([]byte, error) {
+	enc := &jsonEncoder{}
+	value := enc.node(node)
+	if enc.err != nil {
+		return nil, enc.err
+	}
+	return json.Marshal(value)
+}
+
+type jsonNode map[string]interface{}
+
+// jsonEncoder records the first error it runs into, so the per-node cases
+// don't need to check every child
+type jsonEncoder struct {
+	err error
+}
+
+func (e *jsonEncoder) node(node Node) interface{} {
+	if e.err != nil || node == nil {
+		return nil
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		return jsonNode{"kind": "Program", "statements": e.statements(node.Statements)}
+
+	case *LetStatement:
+		return jsonNode{"kind": "LetStatement", "name": e.identifier(node.Name), "value": e.node(node.Value)}
+
+	case *DestructuringStatement:
+		return jsonNode{
+			"kind":  "DestructuringStatement",
+			"names": e.identifiers(node.Names),
+			"hash":  node.Hash,
+			"value": e.node(node.Value),
+		}
+
+	case *ReturnStatement:
+		return jsonNode{"kind": "ReturnStatement", "returnValue": e.node(node.ReturnValue)}
+
+	case *ExpressionStatement:
+		return jsonNode{"kind": "ExpressionStatement", "expression": e.node(node.Expression)}
+
+	case *BlockStatement:
+		return jsonNode{"kind": "BlockStatement", "statements": e.statements(node.Statements)}
+
+	case *ForStatement:
+		return jsonNode{
+			"kind":      "ForStatement",
+			"init":      e.node(node.Init),
+			"condition": e.node(node.Condition),
+			"post":      e.node(node.Post),
+			"body":      e.block(node.Body),
+		}
+
+	case *WhileStatement:
+		return jsonNode{"kind": "WhileStatement", "condition": e.node(node.Condition), "body": e.block(node.Body)}
+
+	case *MatchExpression:
+		arms := make([]interface{}, len(node.Arms))
+		for i, arm := range node.Arms {
+			arms[i] = jsonNode{"pattern": e.node(arm.Pattern), "value": e.node(arm.Value)}
+		}
+		return jsonNode{"kind": "MatchExpression", "subject": e.node(node.Subject), "arms": arms}
+
+	case *DoExpression:
+		return jsonNode{"kind": "DoExpression", "body": e.block(node.Body)}
+
+	case *BreakStatement:
+		return jsonNode{"kind": "BreakStatement"}
+
+	case *ContinueStatement:
+		return jsonNode{"kind": "ContinueStatement"}
+
+	case *Identifier:
+		return e.identifier(node)
+
+	case *IntegerLiteral:
+		return jsonNode{"kind": "IntegerLiteral", "value": node.Value}
+
+	case *BigIntegerLiteral:
+		return jsonNode{"kind": "BigIntegerLiteral", "value": node.Value}
+
+	case *FloatLiteral:
+		return jsonNode{"kind": "FloatLiteral", "value": node.Value}
+
+	case *BooleanExpression:
+		return jsonNode{"kind": "BooleanExpression", "value": node.Value}
+
+	case *NullLiteral:
+		return jsonNode{"kind": "NullLiteral"}
+
+	case *StringLiteral:
+		return jsonNode{"kind": "StringLiteral", "value": node.Value}
+
+	case *InterpolatedString:
+		return jsonNode{"kind": "InterpolatedString", "parts": e.expressions(node.Parts)}
+
+	case *PrefixExpression:
+		return jsonNode{"kind": "PrefixExpression", "operator": node.Operator, "right": e.node(node.Right)}
+
+	case *InfixExpression:
+		return jsonNode{
+			"kind":     "InfixExpression",
+			"operator": node.Operator,
+			"left":     e.node(node.Left),
+			"right":    e.node(node.Right),
+		}
+
+	case *ComparisonChain:
+		return jsonNode{"kind": "ComparisonChain", "operands": e.expressions(node.Operands), "operators": node.Operators}
+
+	case *IfExpression:
+		return jsonNode{
+			"kind":        "IfExpression",
+			"condition":   e.node(node.Condition),
+			"consequence": e.block(node.Consequence),
+			"alternative": e.block(node.Alternative),
+		}
+
+	case *TernaryExpression:
+		return jsonNode{
+			"kind":        "TernaryExpression",
+			"condition":   e.node(node.Condition),
+			"consequence": e.node(node.Consequence),
+			"alternative": e.node(node.Alternative),
+		}
+
+	case *FunctionLiteral:
+		defaults := make([]interface{}, len(node.Parameters))
+		for i := range node.Parameters {
+			if i < len(node.Defaults) {
+				defaults[i] = e.node(node.Defaults[i])
+			}
+		}
+		return jsonNode{
+			"kind":       "FunctionLiteral",
+			"name":       node.Name,
+			"parameters": e.identifiers(node.Parameters),
+			"defaults":   defaults,
+			"rest":       e.identifier(node.Rest),
+			"body":       e.block(node.Body),
+		}
+
+	case *MacroLiteral:
+		return jsonNode{"kind": "MacroLiteral", "parameters": e.identifiers(node.Parameters), "body": e.block(node.Body)}
+
+	case *CallExpression:
+		return jsonNode{"kind": "CallExpression", "function": e.node(node.Function), "arguments": e.expressions(node.Arguments)}
+
+	case *ArrayLiteral:
+		return jsonNode{"kind": "ArrayLiteral", "elements": e.expressions(node.Elements)}
+
+	case *IndexExpression:
+		return jsonNode{"kind": "IndexExpression", "left": e.node(node.Left), "index": e.node(node.Index)}
+
+	case *SliceExpression:
+		return jsonNode{"kind": "SliceExpression", "left": e.node(node.Left), "low": e.node(node.Low), "high": e.node(node.High)}
+
+	case *PostfixExpression:
+		return jsonNode{"kind": "PostfixExpression", "operator": node.Operator, "target": e.node(node.Target)}
+
+	case *AssignExpression:
+		return jsonNode{"kind": "AssignExpression", "target": e.node(node.Target), "value": e.node(node.Value)}
+
+	case *HashLiteral:
+		ordered := node.OrderedPairs()
+		pairs := make([]interface{}, len(ordered))
+		for i, pair := range ordered {
+			pairs[i] = jsonNode{"key": e.node(pair.Key), "value": e.node(pair.Value)}
+		}
+		return jsonNode{"kind": "HashLiteral", "pairs": pairs}
+	}
+
+	e.err = fmt.Errorf("ast: cannot serialize node of type %T", node)
+	return nil
+}
+
+func (e *jsonEncoder) identifier(ident *Identifier) interface{} {
+	if ident == nil {
+		return nil
+	}
+	return jsonNode{"kind": "Identifier", "value": ident.Value}
+}
+
+func (e *jsonEncoder) block(block *BlockStatement) interface{} {
+	if block == nil {
+		return nil
+	}
+	return e.node(block)
+}
+
+func (e *jsonEncoder) identifiers(idents []*Identifier) []interface{} {
+	values := make([]interface{}, len(idents))
+	for i, ident := range idents {
+		values[i] = e.identifier(ident)
+	}
+	return values
+}
+
+func (e *jsonEncoder) statements(statements []Statement) []interface{} {
+	values := make([]interface{}, len(statements))
+	for i, statement := range statements {
+		values[i] = e.node(statement)
+	}
+	return values
+}
+
+func (e *jsonEncoder) expressions(expressions []Expression) []interface{} {
+	values := make([]interface{}, len(expressions))
+	for i, expression := range expressions {
+		values[i] = e.node(expression)
+	}
+	return values
+}
@@ -0,0 +1,139 @@
+package ast
+
+import (
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "x"},
+				Value: &CallExpression{
+					Function: &Identifier{Value: "add"},
+					Arguments: []Expression{
+						&IntegerLiteral{Value: 1},
+						&ArrayLiteral{Elements: []Expression{&BooleanExpression{Value: true}}},
+					},
+				},
+			},
+			&ExpressionStatement{
+				Expression: &IfExpression{
+					Condition: &PrefixExpression{Operator: "!", Right: &Identifier{Value: "x"}},
+					Consequence: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{Expression: &StringLiteral{Value: "y"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := `{"kind":"Program","statements":[` +
+		`{"kind":"LetStatement","name":{"kind":"Identifier","value":"x"},"value":` +
+		`{"arguments":[{"kind":"IntegerLiteral","value":1},` +
+		`{"elements":[{"kind":"BooleanExpression","value":true}],"kind":"ArrayLiteral"}],` +
+		`"function":{"kind":"Identifier","value":"add"},"kind":"CallExpression"}},` +
+		`{"expression":{"alternative":null,` +
+		`"condition":{"kind":"PrefixExpression","operator":"!","right":{"kind":"Identifier","value":"x"}},` +
+		`"consequence":{"kind":"BlockStatement","statements":[` +
+		`{"expression":{"kind":"StringLiteral","value":"y"},"kind":"ExpressionStatement"}]},` +
+		`"kind":"IfExpression"},"kind":"ExpressionStatement"}]}`
+
+	out, err := ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON returned an error: %v", err)
+	}
+	if string(out) != expected {
+		t.Errorf("ToJSON wrong.\nwant=%s\ngot=%s", expected, out)
+	}
+}
+
+func TestToJSONNodeKinds(t *testing.T) {
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{&BreakStatement{}, `{"kind":"BreakStatement"}`},
+		{
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a "}, &Identifier{Value: "b"}}},
+			`{"kind":"InterpolatedString","parts":[{"kind":"StringLiteral","value":"a "},{"kind":"Identifier","value":"b"}]}`,
+		},
+		{&ReturnStatement{}, `{"kind":"ReturnStatement","returnValue":null}`},
+		{
+			&FunctionLiteral{
+				Name:       "f",
+				Parameters: []*Identifier{{Value: "a"}, {Value: "b"}},
+				Defaults:   []Expression{nil, &IntegerLiteral{Value: 2}},
+				Rest:       &Identifier{Value: "rest"},
+				Body:       &BlockStatement{},
+			},
+			`{"body":{"kind":"BlockStatement","statements":[]},` +
+				`"defaults":[null,{"kind":"IntegerLiteral","value":2}],"kind":"FunctionLiteral","name":"f",` +
+				`"parameters":[{"kind":"Identifier","value":"a"},{"kind":"Identifier","value":"b"}],` +
+				`"rest":{"kind":"Identifier","value":"rest"}}`,
+		},
+		{
+			hashLiteral(
+				&StringLiteral{Value: "b"}, &IntegerLiteral{Value: 2},
+				&StringLiteral{Value: "a"}, &IntegerLiteral{Value: 1},
+			),
+			`{"kind":"HashLiteral","pairs":[` +
+				`{"key":{"kind":"StringLiteral","value":"b"},"value":{"kind":"IntegerLiteral","value":2}},` +
+				`{"key":{"kind":"StringLiteral","value":"a"},"value":{"kind":"IntegerLiteral","value":1}}]}`,
+		},
+		{
+			&HashLiteral{Pairs: map[Expression]Expression{&StringLiteral{Value: "a"}: &IntegerLiteral{Value: 1}}},
+			`{"kind":"HashLiteral","pairs":[` +
+				`{"key":{"kind":"StringLiteral","value":"a"},"value":{"kind":"IntegerLiteral","value":1}}]}`,
+		},
+		{
+			&ForStatement{Body: &BlockStatement{Statements: []Statement{&ContinueStatement{}}}},
+			`{"body":{"kind":"BlockStatement","statements":[{"kind":"ContinueStatement"}]},` +
+				`"condition":null,"init":null,"kind":"ForStatement","post":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := ToJSON(tt.node)
+		if err != nil {
+			t.Errorf("ToJSON returned an error: %v", err)
+			continue
+		}
+		if string(out) != tt.expected {
+			t.Errorf("ToJSON wrong.\nwant=%s\ngot=%s", tt.expected, out)
+		}
+	}
+}
+
+func hashLiteral(keysAndValues ...Expression) *HashLiteral {
+	hl := &HashLiteral{Pairs: map[Expression]Expression{}}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		hl.Pairs[keysAndValues[i]] = keysAndValues[i+1]
+		hl.Keys = append(hl.Keys, keysAndValues[i])
+	}
+	return hl
+}
+
+type unknownNode struct{}
+
+func (u *unknownNode) expressionNode()      {}
+func (u *unknownNode) TokenLiteral() string { return "" }
+func (u *unknownNode) String() string       { return "" }
+
+func TestToJSONUnknownNode(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &unknownNode{}},
+		},
+	}
+
+	_, err := ToJSON(program)
+	if err == nil {
+		t.Fatalf("Expected an error for an unknown node")
+	}
+	if err.Error() != "ast: cannot serialize node of type *ast.unknownNode" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+}
@@ -0,0 +1,203 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree depth-first, replacing every node with the result of
+// calling modifier on it after its children have been modified. Composite
+// nodes are copied rather than changed in place, so the original tree can be
+// modified again later, e.g. a macro body expanded more than once
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		program := *node
+		program.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&program)
+
+	case *ExpressionStatement:
+		stmt := *node
+		stmt.Expression, _ = Modify(node.Expression, modifier).(Expression)
+		return modifier(&stmt)
+
+	case *InfixExpression:
+		infix := *node
+		infix.Left, _ = Modify(node.Left, modifier).(Expression)
+		infix.Right, _ = Modify(node.Right, modifier).(Expression)
+		return modifier(&infix)
+
+	case *ComparisonChain:
+		chain := *node
+		chain.Operands = modifyExpressions(node.Operands, modifier)
+		return modifier(&chain)
+
+	case *PrefixExpression:
+		prefix := *node
+		prefix.Right, _ = Modify(node.Right, modifier).(Expression)
+		return modifier(&prefix)
+
+	case *IndexExpression:
+		index := *node
+		index.Left, _ = Modify(node.Left, modifier).(Expression)
+		index.Index, _ = Modify(node.Index, modifier).(Expression)
+		return modifier(&index)
+
+	case *SliceExpression:
+		slice := *node
+		slice.Left, _ = Modify(node.Left, modifier).(Expression)
+		if node.Low != nil {
+			slice.Low, _ = Modify(node.Low, modifier).(Expression)
+		}
+		if node.High != nil {
+			slice.High, _ = Modify(node.High, modifier).(Expression)
+		}
+		return modifier(&slice)
+
+	case *PostfixExpression:
+		postfix := *node
+		postfix.Target, _ = Modify(node.Target, modifier).(Expression)
+		return modifier(&postfix)
+
+	case *AssignExpression:
+		assign := *node
+		assign.Target, _ = Modify(node.Target, modifier).(Expression)
+		assign.Value, _ = Modify(node.Value, modifier).(Expression)
+		return modifier(&assign)
+
+	case *IfExpression:
+		ifExpr := *node
+		ifExpr.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		ifExpr.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.Alternative != nil {
+			ifExpr.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+		}
+		return modifier(&ifExpr)
+
+	case *TernaryExpression:
+		ternary := *node
+		ternary.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		ternary.Consequence, _ = Modify(node.Consequence, modifier).(Expression)
+		ternary.Alternative, _ = Modify(node.Alternative, modifier).(Expression)
+		return modifier(&ternary)
+
+	case *BlockStatement:
+		block := *node
+		block.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&block)
+
+	case *ForStatement:
+		forStmt := *node
+		if node.Init != nil {
+			forStmt.Init, _ = Modify(node.Init, modifier).(Statement)
+		}
+		if node.Condition != nil {
+			forStmt.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		}
+		if node.Post != nil {
+			forStmt.Post, _ = Modify(node.Post, modifier).(Statement)
+		}
+		forStmt.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		return modifier(&forStmt)
+
+	case *MatchExpression:
+		match := *node
+		match.Subject, _ = Modify(node.Subject, modifier).(Expression)
+		match.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			value, _ := Modify(arm.Value, modifier).(Expression)
+			match.Arms[i] = &MatchArm{Pattern: arm.Pattern, Value: value}
+		}
+		return modifier(&match)
+
+	case *DoExpression:
+		do := *node
+		do.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		return modifier(&do)
+
+	case *WhileStatement:
+		while := *node
+		while.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		while.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		return modifier(&while)
+
+	case *ReturnStatement:
+		ret := *node
+		if node.ReturnValue != nil {
+			ret.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+		}
+		return modifier(&ret)
+
+	case *LetStatement:
+		let := *node
+		let.Value, _ = Modify(node.Value, modifier).(Expression)
+		return modifier(&let)
+
+	case *DestructuringStatement:
+		let := *node
+		let.Value, _ = Modify(node.Value, modifier).(Expression)
+		return modifier(&let)
+
+	case *FunctionLiteral:
+		function := *node
+		function.Parameters = make([]*Identifier, len(node.Parameters))
+		for i, param := range node.Parameters {
+			function.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+		}
+		if node.Defaults != nil {
+			function.Defaults = make([]Expression, len(node.Defaults))
+			for i, def := range node.Defaults {
+				if def != nil {
+					function.Defaults[i], _ = Modify(def, modifier).(Expression)
+				}
+			}
+		}
+		function.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		return modifier(&function)
+
+	case *CallExpression:
+		call := *node
+		call.Function, _ = Modify(node.Function, modifier).(Expression)
+		call.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&call)
+
+	case *InterpolatedString:
+		str := *node
+		str.Parts = modifyExpressions(node.Parts, modifier)
+		return modifier(&str)
+
+	case *ArrayLiteral:
+		array := *node
+		array.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&array)
+
+	case *HashLiteral:
+		hash := *node
+		pairs := node.OrderedPairs()
+		hash.Pairs = make(map[Expression]Expression)
+		hash.Keys = make([]Expression, len(pairs))
+		for i, pair := range pairs {
+			newKey, _ := Modify(pair.Key, modifier).(Expression)
+			newVal, _ := Modify(pair.Value, modifier).(Expression)
+			hash.Pairs[newKey] = newVal
+			hash.Keys[i] = newKey
+		}
+		return modifier(&hash)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	modified := make([]Statement, len(statements))
+	for i, statement := range statements {
+		modified[i], _ = Modify(statement, modifier).(Statement)
+	}
+	return modified
+}
+
+func modifyExpressions(expressions []Expression, modifier ModifierFunc) []Expression {
+	modified := make([]Expression, len(expressions))
+	for i, expression := range expressions {
+		modified[i], _ = Modify(expression, modifier).(Expression)
+	}
+	return modified
+}
@@ -0,0 +1,225 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&AssignExpression{Target: &IndexExpression{Left: one(), Index: one()}, Value: one()},
+			&AssignExpression{Target: &IndexExpression{Left: two(), Index: two()}, Value: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&TernaryExpression{Condition: one(), Consequence: one(), Alternative: one()},
+			&TernaryExpression{Condition: two(), Consequence: two(), Alternative: two()},
+		},
+		{
+			&WhileStatement{
+				Condition: one(),
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&WhileStatement{
+				Condition: two(),
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Defaults:   []Expression{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Defaults:   []Expression{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), one()}},
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two(), two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&DoExpression{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}},
+			&DoExpression{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		equal := reflect.DeepEqual(modified, tt.expected)
+		if !equal {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+
+	modified := Modify(hashLiteral(one(), one(), one(), one()), turnOneIntoTwo).(*HashLiteral)
+
+	if len(modified.Keys) != 2 || len(modified.Pairs) != 2 {
+		t.Fatalf("wrong number of pairs. got keys=%d, pairs=%d", len(modified.Keys), len(modified.Pairs))
+	}
+
+	for key, val := range modified.Pairs {
+		key, _ := key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := val.(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
+
+func TestModifyHashLiteralWithoutKeys(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	turnOneIntoTwo := func(node Node) Node {
+		if integer, ok := node.(*IntegerLiteral); ok && integer.Value == 1 {
+			return &IntegerLiteral{Value: 2}
+		}
+		return node
+	}
+
+	hash := &HashLiteral{Pairs: map[Expression]Expression{&StringLiteral{Value: "a"}: one()}}
+	modified := Modify(hash, turnOneIntoTwo).(*HashLiteral)
+
+	if len(modified.Keys) != 1 || len(modified.Pairs) != 1 {
+		t.Fatalf("wrong number of pairs. got keys=%d, pairs=%d", len(modified.Keys), len(modified.Pairs))
+	}
+	val, _ := modified.Pairs[modified.Keys[0]].(*IntegerLiteral)
+	if val == nil || val.Value != 2 {
+		t.Errorf("value is not %d, got=%v", 2, modified.Pairs[modified.Keys[0]])
+	}
+}
+
+func TestModifyLeavesOriginalTree(t *testing.T) {
+	identifier := &Identifier{Value: "x"}
+	original := &InfixExpression{Left: identifier, Operator: "+", Right: identifier}
+
+	replaceIdentifiers := func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return &IntegerLiteral{Value: 1}
+		}
+		return node
+	}
+
+	modified := Modify(original, replaceIdentifiers).(*InfixExpression)
+
+	if _, ok := modified.Left.(*IntegerLiteral); !ok {
+		t.Errorf("modified.Left is not *IntegerLiteral. got=%T", modified.Left)
+	}
+	if original.Left != identifier || original.Right != identifier {
+		t.Errorf("original tree was changed: %#v", original)
+	}
+}
@@ -0,0 +1,162 @@
+package ast
+
+// Walk visits node and everything below it depth-first, in source order.
+// fn is called on a node before its children, which are only visited if it
+// returns true. Missing optional children, like an if without an else, are
+// skipped rather than passed to fn as nil
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		walkStatements(node.Statements, fn)
+
+	case *LetStatement:
+		walkIdentifier(node.Name, fn)
+		walkExpression(node.Value, fn)
+
+	case *DestructuringStatement:
+		for _, name := range node.Names {
+			walkIdentifier(name, fn)
+		}
+		walkExpression(node.Value, fn)
+
+	case *ReturnStatement:
+		walkExpression(node.ReturnValue, fn)
+
+	case *ExpressionStatement:
+		walkExpression(node.Expression, fn)
+
+	case *BlockStatement:
+		walkStatements(node.Statements, fn)
+
+	case *ForStatement:
+		if node.Init != nil {
+			Walk(node.Init, fn)
+		}
+		walkExpression(node.Condition, fn)
+		if node.Post != nil {
+			Walk(node.Post, fn)
+		}
+		walkBlock(node.Body, fn)
+
+	case *WhileStatement:
+		walkExpression(node.Condition, fn)
+		walkBlock(node.Body, fn)
+
+	case *MatchExpression:
+		walkExpression(node.Subject, fn)
+		for _, arm := range node.Arms {
+			walkExpression(arm.Pattern, fn)
+			walkExpression(arm.Value, fn)
+		}
+
+	case *DoExpression:
+		walkBlock(node.Body, fn)
+
+	case *InterpolatedString:
+		walkExpressions(node.Parts, fn)
+
+	case *PrefixExpression:
+		walkExpression(node.Right, fn)
+
+	case *InfixExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Right, fn)
+
+	case *ComparisonChain:
+		walkExpressions(node.Operands, fn)
+
+	case *IfExpression:
+		walkExpression(node.Condition, fn)
+		walkBlock(node.Consequence, fn)
+		walkBlock(node.Alternative, fn)
+
+	case *TernaryExpression:
+		walkExpression(node.Condition, fn)
+		walkExpression(node.Consequence, fn)
+		walkExpression(node.Alternative, fn)
+
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			walkIdentifier(param, fn)
+			if i < len(node.Defaults) {
+				walkExpression(node.Defaults[i], fn)
+			}
+		}
+		walkIdentifier(node.Rest, fn)
+		walkBlock(node.Body, fn)
+
+	case *MacroLiteral:
+		for _, param := range node.Parameters {
+			walkIdentifier(param, fn)
+		}
+		walkBlock(node.Body, fn)
+
+	case *CallExpression:
+		walkExpression(node.Function, fn)
+		walkExpressions(node.Arguments, fn)
+
+	case *ArrayLiteral:
+		walkExpressions(node.Elements, fn)
+
+	case *IndexExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Index, fn)
+
+	case *SliceExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Low, fn)
+		walkExpression(node.High, fn)
+
+	case *PostfixExpression:
+		walkExpression(node.Target, fn)
+
+	case *AssignExpression:
+		walkExpression(node.Target, fn)
+		walkExpression(node.Value, fn)
+
+	case *HashLiteral:
+		for _, pair := range node.OrderedPairs() {
+			walkExpression(pair.Key, fn)
+			walkExpression(pair.Value, fn)
+		}
+	}
+}
+
+// The helpers below keep nil pointers from reaching fn wrapped in a
+// non-nil interface
+
+func walkIdentifier(ident *Identifier, fn func(Node) bool) {
+	if ident != nil {
+		Walk(ident, fn)
+	}
+}
+
+func walkBlock(block *BlockStatement, fn func(Node) bool) {
+	if block != nil {
+		Walk(block, fn)
+	}
+}
+
+func walkExpression(expression Expression, fn func(Node) bool) {
+	if expression != nil {
+		Walk(expression, fn)
+	}
+}
+
+func walkStatements(statements []Statement, fn func(Node) bool) {
+	for _, statement := range statements {
+		if statement != nil {
+			Walk(statement, fn)
+		}
+	}
+}
+
+func walkExpressions(expressions []Expression, fn func(Node) bool) {
+	for _, expression := range expressions {
+		walkExpression(expression, fn)
+	}
+}
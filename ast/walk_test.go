@@ -0,0 +1,130 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	// let add = fn(a, b) { return a + b };
+	// if (add(1, 2) > 2) { [add, {"k": x}] } else { x++ }
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "add"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "a"}, {Value: "b"}},
+					Body: &BlockStatement{
+						Statements: []Statement{
+							&ReturnStatement{ReturnValue: &InfixExpression{
+								Left:     &Identifier{Value: "a"},
+								Operator: "+",
+								Right:    &Identifier{Value: "b"},
+							}},
+						},
+					},
+				},
+			},
+			&ExpressionStatement{
+				Expression: &IfExpression{
+					Condition: &InfixExpression{
+						Left: &CallExpression{
+							Function:  &Identifier{Value: "add"},
+							Arguments: []Expression{&IntegerLiteral{Value: 1}, &IntegerLiteral{Value: 2}},
+						},
+						Operator: ">",
+						Right:    &IntegerLiteral{Value: 2},
+					},
+					Consequence: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{Expression: &ArrayLiteral{
+								Elements: []Expression{
+									&Identifier{Value: "add"},
+									&HashLiteral{
+										Keys:  []Expression{&StringLiteral{Value: "k"}},
+										Pairs: map[Expression]Expression{},
+									},
+								},
+							}},
+						},
+					},
+					Alternative: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{Expression: &PostfixExpression{
+								Target:   &Identifier{Value: "x"},
+								Operator: "++",
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	hash := program.Statements[1].(*ExpressionStatement).Expression.(*IfExpression).
+		Consequence.Statements[0].(*ExpressionStatement).Expression.(*ArrayLiteral).Elements[1].(*HashLiteral)
+	hash.Pairs[hash.Keys[0]] = &Identifier{Value: "x"}
+
+	counts := map[string]int{}
+	Walk(program, func(node Node) bool {
+		counts[fmt.Sprintf("%T", node)]++
+		return true
+	})
+
+	expected := map[string]int{
+		"*ast.Program":             1,
+		"*ast.LetStatement":        1,
+		"*ast.FunctionLiteral":     1,
+		"*ast.BlockStatement":      3,
+		"*ast.ReturnStatement":     1,
+		"*ast.InfixExpression":     2,
+		"*ast.Identifier":          9,
+		"*ast.ExpressionStatement": 3,
+		"*ast.IfExpression":        1,
+		"*ast.CallExpression":      1,
+		"*ast.IntegerLiteral":      3,
+		"*ast.ArrayLiteral":        1,
+		"*ast.HashLiteral":         1,
+		"*ast.StringLiteral":       1,
+		"*ast.PostfixExpression":   1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected node counts %v, instead got %v", expected, counts)
+	}
+
+	// Don't descend into function literals
+	identifiers := []string{}
+	Walk(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			identifiers = append(identifiers, ident.Value)
+		}
+		_, isFunction := node.(*FunctionLiteral)
+		return !isFunction
+	})
+
+	expectedIdentifiers := []string{"add", "add", "add", "x", "x"}
+	if !reflect.DeepEqual(identifiers, expectedIdentifiers) {
+		t.Errorf("Expected identifiers %v, instead got %v", expectedIdentifiers, identifiers)
+	}
+
+	// Hash literals built without the parser may leave Keys empty
+	hash = &HashLiteral{Pairs: map[Expression]Expression{&StringLiteral{Value: "k"}: &Identifier{Value: "v"}}}
+	kinds := []string{}
+	Walk(hash, func(node Node) bool {
+		kinds = append(kinds, fmt.Sprintf("%T", node))
+		return true
+	})
+	expectedKinds := []string{"*ast.HashLiteral", "*ast.StringLiteral", "*ast.Identifier"}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("Expected nodes %v, instead got %v", expectedKinds, kinds)
+	}
+
+	visited := 0
+	Walk(program, func(node Node) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Expected only the root to be visited, instead visited %v nodes", visited)
+	}
+}
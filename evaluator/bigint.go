@@ -0,0 +1,93 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"monkey-interpreter/object"
+)
+
+// maxBigIntegerBits limits how large `**` lets a big integer get, since a
+// small expression like 10 ** 10 ** 10 would otherwise exhaust memory
+const maxBigIntegerBits = 1 << 20
+
+// evalBigIntegerExpression is evalInfixIntegerExpression for operands that
+// don't both fit in 64 bits
+func evalBigIntegerExpression(op string, left object.Object, right object.Object) object.Object {
+	leftVal, rightVal := bigValue(left), bigValue(right)
+
+	switch op {
+	case "+":
+		return newBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return newBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return newBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		// Quo truncates like integer division does
+		return newBigInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			return newError("negative exponent not supported: %v", rightVal)
+		}
+		if !rightVal.IsInt64() {
+			return newError("integer too large")
+		}
+		return bigPower(leftVal, rightVal.Int64())
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %v %v %v",
+			left.Type(), op, right.Type())
+	}
+}
+
+func bigPower(base *big.Int, exp int64) object.Object {
+	if exp > 0 && base.CmpAbs(big.NewInt(1)) > 0 && int64(base.BitLen()-1) > maxBigIntegerBits/exp {
+		return newError("integer too large")
+	}
+	return newBigInteger(new(big.Int).Exp(base, big.NewInt(exp), nil))
+}
+
+// newBigInteger returns an Integer if value fits in one
+func newBigInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return newInteger(value.Int64())
+	}
+	return &object.BigInt{Value: value}
+}
+
+// bigValue returns the value of an Integer or BigInt. The result mustn't be
+// modified, as it may be the BigInt's own
+func bigValue(obj object.Object) *big.Int {
+	if obj, ok := obj.(*object.Integer); ok {
+		return big.NewInt(obj.Value)
+	}
+	return obj.(*object.BigInt).Value
+}
+
+// indexValue returns the value of an integer used as an index. Big integers
+// are out of range of any index, so they're clamped to the nearest int64
+func indexValue(obj object.Object) (int64, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value, true
+	case *object.BigInt:
+		if obj.Value.Sign() < 0 {
+			return math.MinInt64, true
+		}
+		return math.MaxInt64, true
+	}
+	return 0, false
+}
+
+// bigFloat converts a BigInt to the nearest float
+func bigFloat(obj *object.BigInt) float64 {
+	f, _ := new(big.Float).SetInt(obj.Value).Float64()
+	return f
+}
@@ -1,34 +1,146 @@
 package evaluator
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"monkey-interpreter/object"
 )
 
+// checkArgumentCount returns an error if a builtin got a number of arguments
+// other than want
+func checkArgumentCount(args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return newError("wrong number of arguments. got=%v, want=%v)", len(args), want)
+	}
+	return nil
+}
+
+func unsupportedArgument(name string, arg object.Object) *object.Error {
+	// Big integers report their type as INTEGER, so say what's wrong with them
+	if _, ok := arg.(*object.BigInt); ok {
+		return newError("argument to `%v` out of range, got %v", name, arg.Inspect())
+	}
+	return newError("argument to `%v` not supported, got %v", name, arg.Type())
+}
+
 func length(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%v, want=1)", len(args))}
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
 	}
 
 	switch arg := args[0].(type) {
 	case *object.String:
 		return &object.Integer{
-			Value: int64(len(arg.Value)),
+			Value: int64(utf8.RuneCountInString(arg.Value)),
 		}
 
 	case *object.Array:
 		return &object.Integer{
 			Value: int64(len(arg.Elements)),
 		}
+
+	case *object.Hash:
+		return &object.Integer{
+			Value: int64(len(arg.Pairs)),
+		}
 	default:
-		return &object.Error{Message: fmt.Sprintf("argument to `len` not supported, got %v", args[0].Type())}
+		return unsupportedArgument("len", args[0])
+	}
+}
+
+// chars splits a string into single-character strings, one per rune
+func chars(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("chars", args[0])
+	}
+
+	elements := make([]object.Object, 0, utf8.RuneCountInString(str.Value))
+	for _, r := range str.Value {
+		elements = append(elements, &object.String{Value: string(r)})
+	}
+	return &object.Array{Elements: elements}
+}
+
+// stringBytes returns the UTF-8 encoding of a string as an array of integers
+func stringBytes(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("bytes", args[0])
+	}
+
+	elements := make([]object.Object, len(str.Value))
+	for i := 0; i < len(str.Value); i++ {
+		elements[i] = newInteger(int64(str.Value[i]))
+	}
+	return &object.Array{Elements: elements}
+}
+
+// stringValues checks that a builtin got want arguments, all strings, and
+// returns their values
+func stringValues(name string, args []object.Object, want int) ([]string, *object.Error) {
+	if err := checkArgumentCount(args, want); err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, unsupportedArgument(name, arg)
+		}
+		values[i] = str.Value
+	}
+	return values, nil
+}
+
+func startsWith(args ...object.Object) object.Object {
+	values, err := stringValues("startsWith", args, 2)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(strings.HasPrefix(values[0], values[1]))
+}
+
+func endsWith(args ...object.Object) object.Object {
+	values, err := stringValues("endsWith", args, 2)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(strings.HasSuffix(values[0], values[1]))
+}
+
+// replace returns s with every occurrence of old replaced by new
+func replace(args ...object.Object) object.Object {
+	values, err := stringValues("replace", args, 3)
+	if err != nil {
+		return err
 	}
+	return &object.String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
 }
 
 func head(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%v, want=1)", len(args))}
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
 	}
 
 	switch arg := args[0].(type) {
@@ -38,13 +150,13 @@ func head(args ...object.Object) object.Object {
 		}
 		return arg.Elements[0]
 	default:
-		return &object.Error{Message: fmt.Sprintf("argument to `len` not supported, got %v", args[0].Type())}
+		return unsupportedArgument("head", args[0])
 	}
 }
 
 func tail(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%v, want=1)", len(args))}
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
 	}
 
 	switch arg := args[0].(type) {
@@ -54,17 +166,19 @@ func tail(args ...object.Object) object.Object {
 				Elements: []object.Object{},
 			}
 		}
+		elements := make([]object.Object, len(arg.Elements)-1)
+		copy(elements, arg.Elements[1:])
 		return &object.Array{
-			Elements: arg.Elements[1:],
+			Elements: elements,
 		}
 	default:
-		return &object.Error{Message: fmt.Sprintf("argument to `len` not supported, got %v", args[0].Type())}
+		return unsupportedArgument("tail", args[0])
 	}
 }
 
 func last(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%v, want=1)", len(args))}
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
 	}
 
 	switch arg := args[0].(type) {
@@ -74,49 +188,1343 @@ func last(args ...object.Object) object.Object {
 		}
 		return arg.Elements[len(arg.Elements)-1]
 	default:
-		return &object.Error{Message: fmt.Sprintf("argument to `len` not supported, got %v", args[0].Type())}
+		return unsupportedArgument("last", args[0])
 	}
 }
 
+// push returns a new array with every argument after the first appended
 func push(args ...object.Object) object.Object {
-	if len(args) != 2 {
-		return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%v, want=2)", len(args))}
+	if len(args) < 2 {
+		return newError("wrong number of arguments. got=%v, want=2+)", len(args))
 	}
 
 	arr, ok := args[0].(*object.Array)
 	if !ok {
-		return &object.Error{Message: fmt.Sprintf("argument to `push` not supported, got %v", args[0].Type())}
+		return unsupportedArgument("push", args[0])
 	}
 
+	elements := make([]object.Object, len(arr.Elements), len(arr.Elements)+len(args)-1)
+	copy(elements, arr.Elements)
+
 	return &object.Array{
-		Elements: append(arr.Elements, args[1]),
+		Elements: append(elements, args[1:]...),
 	}
 }
 
-func puts(args ...object.Object) object.Object {
+func set(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 3); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("set", args[0])
+	}
+
+	index, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("index to `set` must be INTEGER, got %v", args[1].Type())
+	}
+
+	idx := index.Value
+	if idx < 0 || idx >= int64(len(arr.Elements)) {
+		return newError("index out of range: %v (length %v)", idx, len(arr.Elements))
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+	elements[idx] = args[2]
+
+	return &object.Array{Elements: elements}
+}
+
+// insert returns a new array with value at index and the elements from
+// index onwards shifted back. An index equal to the length appends value
+func insert(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 3); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("insert", args[0])
+	}
+
+	index, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("index to `insert` must be INTEGER, got %v", args[1].Type())
+	}
+
+	idx := index.Value
+	if idx < 0 || idx > int64(len(arr.Elements)) {
+		return newError("index out of range: %v (length %v)", idx, len(arr.Elements))
+	}
+
+	elements := make([]object.Object, 0, len(arr.Elements)+1)
+	elements = append(elements, arr.Elements[:idx]...)
+	elements = append(elements, args[2])
+	elements = append(elements, arr.Elements[idx:]...)
+
+	return &object.Array{Elements: elements}
+}
+
+// remove returns a new array without the element at index
+func remove(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("remove", args[0])
+	}
+
+	index, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("index to `remove` must be INTEGER, got %v", args[1].Type())
+	}
+
+	idx := index.Value
+	if idx < 0 || idx >= int64(len(arr.Elements)) {
+		return newError("index out of range: %v (length %v)", idx, len(arr.Elements))
+	}
+
+	elements := make([]object.Object, 0, len(arr.Elements)-1)
+	elements = append(elements, arr.Elements[:idx]...)
+	elements = append(elements, arr.Elements[idx+1:]...)
+
+	return &object.Array{Elements: elements}
+}
+
+func deleteKey(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return unsupportedArgument("delete", args[0])
+	}
+
+	key, ok := object.AsHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %v", args[1].Type())
+	}
+
+	hashKey := key.HashKey()
+	if _, ok := hash.Pairs[hashKey]; !ok {
+		return hash
+	}
+
+	result := object.NewHash()
+	for _, k := range hash.Keys {
+		if k != hashKey {
+			result.Set(k, hash.Pairs[k])
+		}
+	}
+
+	return result
+}
+
+func reverse(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Array:
+		elements := make([]object.Object, len(arg.Elements))
+		for i, el := range arg.Elements {
+			elements[len(elements)-1-i] = el
+		}
+		return &object.Array{Elements: elements}
+	case *object.String:
+		runes := []rune(arg.Value)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return &object.String{Value: string(runes)}
+	default:
+		return unsupportedArgument("reverse", args[0])
+	}
+}
+
+func sortArray(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("sort", args[0])
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	var err error
+	sort.SliceStable(elements, func(i, j int) bool {
+		cmp, cmpErr := object.Compare(elements[i], elements[j])
+		if cmpErr != nil && err == nil {
+			err = cmpErr
+		}
+		return cmp < 0
+	})
+	if err != nil {
+		return newError("can't sort: %v", err)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func keys(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return unsupportedArgument("keys", args[0])
+	}
+
+	elements := make([]object.Object, 0, len(hash.Keys))
+	for _, pair := range hash.OrderedPairs() {
+		elements = append(elements, pair.Key)
+	}
+	return &object.Array{Elements: elements}
+}
+
+func values(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return unsupportedArgument("values", args[0])
+	}
+
+	elements := make([]object.Object, 0, len(hash.Keys))
+	for _, pair := range hash.OrderedPairs() {
+		elements = append(elements, pair.Value)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// entries returns a hash's pairs as [key, value] arrays, in insertion order
+func entries(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return unsupportedArgument("entries", args[0])
+	}
+
+	elements := make([]object.Object, 0, len(hash.Keys))
+	for _, pair := range hash.OrderedPairs() {
+		elements = append(elements, &object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+	}
+	return &object.Array{Elements: elements}
+}
+
+// merge returns a new hash with the pairs of all its arguments. When a key
+// is in more than one of them, the last value wins but the key stays where
+// it was first seen
+func merge(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want=1+)")
+	}
+
+	result := object.NewHash()
 	for _, arg := range args {
-		fmt.Println(arg.Inspect())
+		hash, ok := arg.(*object.Hash)
+		if !ok {
+			return unsupportedArgument("merge", arg)
+		}
+		for _, key := range hash.Keys {
+			result.Set(key, hash.Pairs[key])
+		}
 	}
-	return NULL
+	return result
 }
 
-var builtins = map[string]*object.Builtin{
-	"len": {
-		Fn: length,
-	},
-	"head": {
-		Fn: head,
-	},
-	"tail": {
-		Fn: tail,
-	},
-	"last": {
-		Fn: last,
-	},
-	"push": {
-		Fn: push,
-	},
-	"puts": {
-		Fn: puts,
-	},
+// unique returns the elements of an array without repeats, in the order
+// they were first seen. Elements are compared with object.Equals, using
+// their hash keys where they have one
+func unique(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("unique", args[0])
+	}
+
+	seen := map[object.HashKey]bool{}
+	var seenOther []object.Object
+	elements := []object.Object{}
+
+outer:
+	for _, el := range arr.Elements {
+		if hashable, ok := object.AsHashable(el); ok {
+			key := hashable.HashKey()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		} else {
+			for _, other := range seenOther {
+				if object.Equals(el, other) {
+					continue outer
+				}
+			}
+			seenOther = append(seenOther, el)
+		}
+		elements = append(elements, el)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// count returns how many elements of an array equal value, comparing the
+// same way unique does
+func count(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("count", args[0])
+	}
+
+	value, hashable := object.AsHashable(args[1])
+	n := 0
+	for _, el := range arr.Elements {
+		if hashable {
+			if h, ok := object.AsHashable(el); ok && h.HashKey() == value.HashKey() {
+				n++
+			}
+		} else if object.Equals(el, args[1]) {
+			n++
+		}
+	}
+	return newInteger(int64(n))
+}
+
+func copyValue(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+	return deepCopy(args[0])
+}
+
+// deepCopy recursively copies arrays and hashes. Everything else is either
+// immutable or shared on purpose, so it's returned as is
+func deepCopy(obj object.Object) object.Object {
+	return deepCopyInto(obj, map[object.Object]object.Object{})
+}
+
+// deepCopyInto is deepCopy, with copies mapping the containers copied so far
+// to their copies. A container that's reached again, such as an array that
+// contains itself, is replaced by its copy, so the copy has the same shape
+func deepCopyInto(obj object.Object, copies map[object.Object]object.Object) object.Object {
+	if copied, ok := copies[obj]; ok {
+		return copied
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		array := &object.Array{Elements: make([]object.Object, len(obj.Elements))}
+		copies[obj] = array
+		for i, el := range obj.Elements {
+			array.Elements[i] = deepCopyInto(el, copies)
+		}
+		return array
+
+	case *object.Hash:
+		hash := object.NewHash()
+		copies[obj] = hash
+		for _, key := range obj.Keys {
+			pair := obj.Pairs[key]
+			hash.Set(key, object.HashPair{Key: pair.Key, Value: deepCopyInto(pair.Value, copies)})
+		}
+		return hash
+
+	default:
+		return obj
+	}
+}
+
+func repeat(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return unsupportedArgument("repeat", args[1])
+	}
+	if n.Value < 0 {
+		return newError("argument to `repeat` must not be negative, got %v", n.Value)
+	}
+
+	elements := make([]object.Object, n.Value)
+	for i := range elements {
+		elements[i] = deepCopy(args[0])
+	}
+	return &object.Array{Elements: elements}
+}
+
+func zip(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	a, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("zip", args[0])
+	}
+	b, ok := args[1].(*object.Array)
+	if !ok {
+		return unsupportedArgument("zip", args[1])
+	}
+
+	n := len(a.Elements)
+	if len(b.Elements) < n {
+		n = len(b.Elements)
+	}
+
+	pairs := make([]object.Object, n)
+	for i := range pairs {
+		pairs[i] = &object.Array{Elements: []object.Object{a.Elements[i], b.Elements[i]}}
+	}
+	return &object.Array{Elements: pairs}
+}
+
+func typeOf(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+	return &object.String{Value: args[0].Type().String()}
+}
+
+// typePredicate builds a builtin reporting whether its single argument is of
+// one of the given types
+func typePredicate(types ...object.ObjectType) object.BuiltinFn {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(args, 1); err != nil {
+			return err
+		}
+		for _, t := range types {
+			if args[0].Type() == t {
+				return TRUE
+			}
+		}
+		return FALSE
+	}
+}
+
+// arity returns how many parameters a function declares, counting those with
+// defaults. Functions with a rest parameter and builtins, whose arity isn't
+// known, return -1
+func arity(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		if fn.Rest != nil {
+			return newInteger(-1)
+		}
+		return newInteger(int64(len(fn.Parameters)))
+	case *object.Builtin:
+		return newInteger(-1)
+	}
+	return unsupportedArgument("arity", args[0])
+}
+
+func slice(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%v, want=2..3)", len(args))
+	}
+
+	bounds := []int64{}
+	for _, arg := range args[1:] {
+		integer, ok := indexValue(arg)
+		if !ok {
+			return newError("index to `slice` must be INTEGER, got %v", arg.Type())
+		}
+		bounds = append(bounds, integer)
+	}
+
+	if result := sliceValue(args[0], bounds); result != nil {
+		return result
+	}
+	return unsupportedArgument("slice", args[0])
+}
+
+// sliceValue copies part of an array or string, as described by
+// sliceBounds. It returns nil for other objects
+func sliceValue(obj object.Object, bounds []int64) object.Object {
+	switch obj := obj.(type) {
+	case *object.Array:
+		start, end := sliceBounds(bounds, int64(len(obj.Elements)))
+		elements := make([]object.Object, end-start)
+		copy(elements, obj.Elements[start:end])
+		return &object.Array{Elements: elements}
+	case *object.String:
+		runes := []rune(obj.Value)
+		start, end := sliceBounds(bounds, int64(len(runes)))
+		return &object.String{Value: string(runes[start:end])}
+	}
+	return nil
+}
+
+// sliceBounds turns a start and an optional end index into a valid half-open
+// range, counting negative indices from the end and clamping to the length
+func sliceBounds(bounds []int64, length int64) (int64, int64) {
+	clamp := func(i int64) int64 {
+		if i < 0 {
+			i += length
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > length {
+			return length
+		}
+		return i
+	}
+
+	start, end := clamp(bounds[0]), length
+	if len(bounds) > 1 {
+		end = clamp(bounds[1])
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+// integerValues accepts either integer arguments or a single array of
+// integers. Big integers are included
+func integerValues(name string, args []object.Object) ([]object.Object, *object.Error) {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			args = arr.Elements
+		}
+	}
+
+	for _, arg := range args {
+		if arg.Type() != object.INTEGER_OBJ {
+			return nil, newError("argument to `%v` not supported, got %v", name, arg.Type())
+		}
+	}
+	return args, nil
+}
+
+func minimum(args ...object.Object) object.Object {
+	values, err := integerValues("min", args)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return newError("`min` needs at least one value")
+	}
+
+	result := values[0]
+	for _, v := range values[1:] {
+		if cmp, _ := object.Compare(v, result); cmp < 0 {
+			result = v
+		}
+	}
+	return result
+}
+
+func maximum(args ...object.Object) object.Object {
+	values, err := integerValues("max", args)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return newError("`max` needs at least one value")
+	}
+
+	result := values[0]
+	for _, v := range values[1:] {
+		if cmp, _ := object.Compare(v, result); cmp > 0 {
+			result = v
+		}
+	}
+	return result
+}
+
+func abs(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	switch integer := args[0].(type) {
+	case *object.Integer:
+		if integer.Value < 0 {
+			return evalMinusPrefixOperatorExpression(integer)
+		}
+		return integer
+	case *object.BigInt:
+		return newBigInteger(new(big.Int).Abs(integer.Value))
+	}
+	return unsupportedArgument("abs", args[0])
+}
+
+// rounding builds a builtin that turns a float into an integer using fn.
+// Integers are returned unchanged. `round` uses math.Round, so halves are
+// rounded away from zero: round(2.5) is 3 and round(-2.5) is -3
+func rounding(name string, fn func(float64) float64) object.BuiltinFn {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(args, 1); err != nil {
+			return err
+		}
+
+		switch arg := args[0].(type) {
+		case *object.Integer, *object.BigInt:
+			return arg
+		case *object.Float:
+			value := fn(arg.Value)
+			if math.IsNaN(value) || value < math.MinInt64 || value >= -math.MinInt64 {
+				return newError("argument to `%v` out of range, got %v", name, arg.Inspect())
+			}
+			return newInteger(int64(value))
+		default:
+			return unsupportedArgument(name, args[0])
+		}
+	}
+}
+
+// mathFunction builds a builtin applying fn to an integer or float. NaN
+// results mean the argument was outside of fn's domain, e.g. sqrt(-1), and
+// infinite ones from finite arguments that it was a pole, e.g. log(0), or
+// that the result was too large
+func mathFunction(name string, fn func(float64) float64) object.BuiltinFn {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(args, 1); err != nil {
+			return err
+		}
+
+		var x float64
+		switch arg := args[0].(type) {
+		case *object.Integer:
+			x = float64(arg.Value)
+		case *object.BigInt:
+			x = bigFloat(arg)
+		case *object.Float:
+			x = arg.Value
+		default:
+			return unsupportedArgument(name, args[0])
+		}
+
+		result := fn(x)
+		if math.IsNaN(result) || (math.IsInf(result, 0) && !math.IsInf(x, 0)) {
+			return newError("argument to `%v` out of domain, got %v", name, args[0].Inspect())
+		}
+		return &object.Float{Value: result}
+	}
+}
+
+// mathConstant builds a builtin returning value
+func mathConstant(value float64) object.BuiltinFn {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(args, 0); err != nil {
+			return err
+		}
+		return &object.Float{Value: value}
+	}
+}
+
+func sum(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	if _, ok := args[0].(*object.Array); !ok {
+		return unsupportedArgument("sum", args[0])
+	}
+
+	values, err := integerValues("sum", args)
+	if err != nil {
+		return err
+	}
+
+	var result object.Object = newInteger(0)
+	for _, v := range values {
+		result = evalInfixIntegerExpression("+", result, v)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+func pow(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if arg.Type() != object.INTEGER_OBJ {
+			return newError("argument to `pow` not supported, got %v", arg.Type())
+		}
+	}
+
+	return evalInfixIntegerExpression("**", args[0], args[1])
+}
+
+// Now is the time source used by `clock`. Embedders and tests can replace it
+var Now = time.Now
+
+func clock(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 0); err != nil {
+		return err
+	}
+	return &object.Integer{Value: Now().UnixNano() / int64(time.Millisecond)}
+}
+
+// random backs `rand` and `seed`. It's separate from the global math/rand
+// source so that seeding it doesn't affect other users of that source
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func randomInt(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	if n, ok := args[0].(*object.BigInt); ok {
+		if n.Value.Sign() <= 0 {
+			return newError("argument to `rand` must be positive, got %v", n.Inspect())
+		}
+		return newBigInteger(new(big.Int).Rand(random, n.Value))
+	}
+
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return unsupportedArgument("rand", args[0])
+	}
+	if n.Value <= 0 {
+		return newError("argument to `rand` must be positive, got %v", n.Value)
+	}
+
+	return newInteger(random.Int63n(n.Value))
+}
+
+func seed(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	x, ok := args[0].(*object.Integer)
+	if !ok {
+		return unsupportedArgument("seed", args[0])
+	}
+
+	random.Seed(x.Value)
+	return NULL
+}
+
+// Stdout is where `puts` writes to. Embedders and tests can replace it
+var Stdout io.Writer = os.Stdout
+
+func puts(args ...object.Object) object.Object {
+	for _, arg := range args {
+		fmt.Fprintln(Stdout, arg.Inspect())
+	}
+	return NULL
+}
+
+// stdin is where `readline` reads from. Reading through a single buffer
+// means lines aren't lost between calls
+var stdin = bufio.NewReader(os.Stdin)
+
+// SetStdin makes `readline` read from r. Embedders and tests can use it to
+// replace standard input
+func SetStdin(r io.Reader) {
+	stdin = bufio.NewReader(r)
+}
+
+// readline returns the next line of input without its line ending, or
+// NULL once the input is exhausted
+func readline(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 0); err != nil {
+		return err
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		if err == io.EOF {
+			return NULL
+		}
+		return newError("could not read input: %v", err)
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &object.String{Value: line}
+}
+
+// These builtins refer back to Eval, so they're set up in init to avoid an
+// initialization cycle
+func init() {
+	// Builtins that need the environment they're called from
+	builtins["eval"] = &object.Builtin{EnvFn: evalString}
+	builtins["load"] = &object.Builtin{EnvFn: loadFile}
+	builtins["vars"] = &object.Builtin{EnvFn: vars}
+	builtins["unset"] = &object.Builtin{EnvFn: unset}
+
+	// Builtins that call back into Monkey functions
+	builtins["each"] = &object.Builtin{Fn: each}
+	builtins["mapValues"] = &object.Builtin{Fn: mapValues}
+	builtins["curry"] = &object.Builtin{Fn: curry}
+	builtins["try"] = &object.Builtin{Fn: try}
+	builtins["groupBy"] = &object.Builtin{Fn: groupBy}
+	builtins["countBy"] = &object.Builtin{Fn: countBy}
+	builtins["memoize"] = &object.Builtin{Fn: memoize}
+}
+
+// each calls fn(key, value) for every pair of a hash, in insertion order
+func each(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return unsupportedArgument("each", args[0])
+	}
+
+	for _, pair := range hash.OrderedPairs() {
+		result := applyFunction(args[1], []object.Object{pair.Key, pair.Value})
+		if isError(result) {
+			return result
+		}
+	}
+	return NULL
+}
+
+// mapValues returns a new hash with fn(value) in place of every value
+func mapValues(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return unsupportedArgument("mapValues", args[0])
+	}
+
+	result := object.NewHash()
+	for _, key := range hash.Keys {
+		pair := hash.Pairs[key]
+		val := applyFunction(args[1], []object.Object{pair.Value})
+		if isError(val) {
+			return val
+		}
+		result.Set(key, object.HashPair{Key: pair.Key, Value: val})
+	}
+	return result
+}
+
+// curry lets a function take its arguments over several calls. Once at
+// least its number of required arguments has been collected, the function
+// is called with all of them. The arity of builtins isn't known, so it has
+// to be passed as the second argument. Calling a function directly with
+// too few arguments is still an error
+func curry(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%v, want=1..2)", len(args))
+	}
+
+	var arity int
+	switch fn := args[0].(type) {
+	case *object.Function:
+		arity = requiredArguments(fn)
+	case *object.Builtin:
+		if len(args) != 2 {
+			return newError("`curry` needs the arity of a builtin")
+		}
+	default:
+		return unsupportedArgument("curry", args[0])
+	}
+
+	if len(args) == 2 {
+		n, ok := args[1].(*object.Integer)
+		if !ok {
+			return unsupportedArgument("curry", args[1])
+		}
+		if n.Value < 0 {
+			return newError("argument to `curry` must not be negative, got %v", n.Value)
+		}
+		arity = int(n.Value)
+	}
+
+	return curried(args[0], arity, nil)
+}
+
+func curried(fn object.Object, arity int, collected []object.Object) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		all := make([]object.Object, 0, len(collected)+len(args))
+		all = append(all, collected...)
+		all = append(all, args...)
+		if len(all) >= arity {
+			return applyFunction(fn, all)
+		}
+		return curried(fn, arity, all)
+	}}
+}
+
+// try calls fn with no arguments. If that produces an error, handler is
+// called with the error and its result is returned instead. Using the error
+// inside handler, or returning it, raises it again
+func try(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		switch arg.(type) {
+		case *object.Function, *object.Builtin:
+		default:
+			return unsupportedArgument("try", arg)
+		}
+	}
+
+	result := applyFunction(args[0], []object.Object{})
+	if !isError(result) {
+		return result
+	}
+
+	return applyFunction(args[1], []object.Object{result})
+}
+
+// groupBy returns a hash from every distinct fn(element) to the elements
+// that produced it, in their original order. Groups are ordered by when
+// their key was first produced
+func groupBy(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("groupBy", args[0])
+	}
+
+	result := object.NewHash()
+	for _, el := range arr.Elements {
+		key := applyFunction(args[1], []object.Object{el})
+		if isError(key) {
+			return key
+		}
+
+		hashable, ok := object.AsHashable(key)
+		if !ok {
+			return newError("unusable as hash key: %v", key.Type())
+		}
+
+		hashKey := hashable.HashKey()
+		if pair, ok := result.Pairs[hashKey]; ok {
+			group := pair.Value.(*object.Array)
+			group.Elements = append(group.Elements, el)
+			continue
+		}
+		result.Set(hashKey, object.HashPair{Key: key, Value: &object.Array{Elements: []object.Object{el}}})
+	}
+	return result
+}
+
+// countBy returns a hash from every distinct fn(element) to how many
+// elements produced it
+func countBy(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 2); err != nil {
+		return err
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return unsupportedArgument("countBy", args[0])
+	}
+
+	result := object.NewHash()
+	for _, el := range arr.Elements {
+		key := applyFunction(args[1], []object.Object{el})
+		if isError(key) {
+			return key
+		}
+
+		hashable, ok := object.AsHashable(key)
+		if !ok {
+			return newError("unusable as hash key: %v", key.Type())
+		}
+
+		hashKey := hashable.HashKey()
+		n := int64(1)
+		if pair, ok := result.Pairs[hashKey]; ok {
+			n += pair.Value.(*object.Integer).Value
+		}
+		result.Set(hashKey, object.HashPair{Key: key, Value: newInteger(n)})
+	}
+	return result
+}
+
+// memoize wraps fn so calls remember their results. Arguments are looked up
+// by value, so they all have to be usable as hash keys for the result to be
+// remembered; other calls go straight to fn. Errors aren't remembered
+func memoize(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	fn := args[0]
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return unsupportedArgument("memoize", fn)
+	}
+
+	// The pair's key holds the arguments, in case their hash keys collide
+	cache := map[object.HashKey]object.HashPair{}
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		key := &object.Array{Elements: args}
+		if !key.IsHashable() {
+			return applyFunction(fn, args)
+		}
+
+		hashKey := key.HashKey()
+		if pair, ok := cache[hashKey]; ok && object.Equals(pair.Key, key) {
+			return pair.Value
+		}
+
+		result := applyFunction(fn, args)
+		if !isError(result) {
+			cache[hashKey] = object.HashPair{Key: key, Value: result}
+		}
+		return result
+	}}
+}
+
+// unset removes the innermost binding of a variable, returning whether there
+// was one
+func unset(env *object.Environment, args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("unset", args[0])
+	}
+
+	return nativeBoolToBooleanObject(env.Delete(name.Value))
+}
+
+// vars lists the names of the variables visible from where it's called.
+// Builtins aren't included unless a variable shadows them
+func vars(env *object.Environment, args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 0); err != nil {
+		return err
+	}
+
+	keys := env.Keys()
+	elements := make([]object.Object, len(keys))
+	for i, key := range keys {
+		elements[i] = &object.String{Value: key}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func evalString(env *object.Environment, args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	source, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("eval", args[0])
+	}
+
+	defer env.LeaveCall()
+	if env.EnterCall() > MaxCallDepth {
+		return newError("maximum recursion depth exceeded")
+	}
+
+	return evalSource(source.Value, env)
+}
+
+func evalSource(source string, env *object.Environment) object.Object {
+	p := newParser(source)
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return newError("parser errors: %s", strings.Join(errors, "; "))
+	}
+
+	if result := Eval(program, env); result != nil {
+		return result
+	}
+	return NULL
+}
+
+// parseSource returns the canonical form of the parsed source, with every
+// operation parenthesized, so `parse("1 + 2 * 3")` is "(1 + (2 * 3))"
+func parseSource(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	source, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("parse", args[0])
+	}
+
+	p := newParser(source.Value)
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return newError("parser errors: %s", strings.Join(errors, "; "))
+	}
+	return &object.String{Value: program.String()}
+}
+
+// loading holds the absolute paths of the files currently being loaded, to
+// catch files that load each other
+var loading = map[string]bool{}
+
+func loadFile(env *object.Environment, args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("load", args[0])
+	}
+
+	absPath, err := filepath.Abs(path.Value)
+	if err != nil {
+		return newError("could not load %v: %v", path.Value, err)
+	}
+	if loading[absPath] {
+		return newError("cyclic load of %v", path.Value)
+	}
+
+	source, err := os.ReadFile(absPath)
+	if os.IsNotExist(err) {
+		return newError("file not found: %v", path.Value)
+	}
+	if err != nil {
+		return newError("could not load %v: %v", path.Value, err)
+	}
+
+	loading[absPath] = true
+	defer delete(loading, absPath)
+
+	return evalSource(string(source), env)
+}
+
+var builtins = map[string]*object.Builtin{
+	"len": {
+		Fn: length,
+	},
+	"chars": {
+		Fn: chars,
+	},
+	"bytes": {
+		Fn: stringBytes,
+	},
+	"startsWith": {
+		Fn: startsWith,
+	},
+	"endsWith": {
+		Fn: endsWith,
+	},
+	"replace": {
+		Fn: replace,
+	},
+	"head": {
+		Fn: head,
+	},
+	"first": {
+		Fn: head,
+	},
+	"tail": {
+		Fn: tail,
+	},
+	"rest": {
+		Fn: tail,
+	},
+	"last": {
+		Fn: last,
+	},
+	"push": {
+		Fn: push,
+	},
+	"puts": {
+		Fn: puts,
+	},
+	"readline": {
+		Fn: readline,
+	},
+	"set": {
+		Fn: set,
+	},
+	"insert": {
+		Fn: insert,
+	},
+	"remove": {
+		Fn: remove,
+	},
+	"delete": {
+		Fn: deleteKey,
+	},
+	"slice": {
+		Fn: slice,
+	},
+	"reverse": {
+		Fn: reverse,
+	},
+	"copy": {
+		Fn: copyValue,
+	},
+	"repeat": {
+		Fn: repeat,
+	},
+	"zip": {
+		Fn: zip,
+	},
+	"type": {
+		Fn: typeOf,
+	},
+	"isInt": {
+		Fn: typePredicate(object.INTEGER_OBJ),
+	},
+	"isString": {
+		Fn: typePredicate(object.STRING_OBJ),
+	},
+	"isArray": {
+		Fn: typePredicate(object.ARRAY_OBJ),
+	},
+	"isHash": {
+		Fn: typePredicate(object.HASH_OBJ),
+	},
+	"isFunction": {
+		Fn: typePredicate(object.FUNCTION_OBJ, object.BUILTIN_OBJ),
+	},
+	"arity": {
+		Fn: arity,
+	},
+	"isNull": {
+		Fn: typePredicate(object.NULL_OBJ),
+	},
+	"isBool": {
+		Fn: typePredicate(object.BOOLEAN_OBJ),
+	},
+	"sort": {
+		Fn: sortArray,
+	},
+	"clock": {
+		Fn: clock,
+	},
+	"rand": {
+		Fn: randomInt,
+	},
+	"seed": {
+		Fn: seed,
+	},
+	"keys": {
+		Fn: keys,
+	},
+	"values": {
+		Fn: values,
+	},
+	"entries": {
+		Fn: entries,
+	},
+	"merge": {
+		Fn: merge,
+	},
+	"unique": {
+		Fn: unique,
+	},
+	"count": {
+		Fn: count,
+	},
+	"min": {
+		Fn: minimum,
+	},
+	"max": {
+		Fn: maximum,
+	},
+	"abs": {
+		Fn: abs,
+	},
+	"floor": {
+		Fn: rounding("floor", math.Floor),
+	},
+	"ceil": {
+		Fn: rounding("ceil", math.Ceil),
+	},
+	"round": {
+		Fn: rounding("round", math.Round),
+	},
+	"sqrt": {
+		Fn: mathFunction("sqrt", math.Sqrt),
+	},
+	"log": {
+		Fn: mathFunction("log", math.Log),
+	},
+	"log10": {
+		Fn: mathFunction("log10", math.Log10),
+	},
+	"exp": {
+		Fn: mathFunction("exp", math.Exp),
+	},
+	"sin": {
+		Fn: mathFunction("sin", math.Sin),
+	},
+	"cos": {
+		Fn: mathFunction("cos", math.Cos),
+	},
+	"tan": {
+		Fn: mathFunction("tan", math.Tan),
+	},
+	"pi": {
+		Fn: mathConstant(math.Pi),
+	},
+	"e": {
+		Fn: mathConstant(math.E),
+	},
+	"sum": {
+		Fn: sum,
+	},
+	"pow": {
+		Fn: pow,
+	},
+	"json": {
+		Fn: toJSON,
+	},
+	"parseJSON": {
+		Fn: parseJSON,
+	},
+	"parse": {
+		Fn: parseSource,
+	},
+}
+
+// RegisterBuiltin makes fn callable from Monkey code as name. Registering a
+// name that already exists replaces the previous builtin, including the ones
+// shipped with the interpreter. Like other bindings, builtins are shadowed by
+// variables of the same name.
+//
+// RegisterBuiltin is not safe to call concurrently with Eval
+func RegisterBuiltin(name string, fn object.BuiltinFn) {
+	builtins[name] = &object.Builtin{Fn: fn}
 }
@@ -2,18 +2,53 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 
 	"monkey-interpreter/ast"
 	"monkey-interpreter/object"
+	"monkey-interpreter/token"
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// MaxCallDepth limits how deep Monkey function calls can nest, so runaway
+// recursion produces an error instead of overflowing the Go stack
+var MaxCallDepth = 10000
+
+// maxStackFrames limits how many frames an error keeps, so errors from
+// runaway recursion stay readable
+const maxStackFrames = 20
+
+// CheckOverflow makes integer arithmetic that would wrap around return an
+// "integer overflow" error. Turning it off trades that safety for slightly
+// faster arithmetic
+var CheckOverflow = true
+
+// BigIntegers makes integer arithmetic that doesn't fit in 64 bits carry on
+// with arbitrary precision instead, taking precedence over CheckOverflow.
+// Results that fit are turned back into regular integers
+var BigIntegers = false
+
+// FalsyEmptyValues makes conditions and `!` treat 0, 0.0, "", [] and {} as
+// false, like many other languages do. By default only false and null are
+var FalsyEmptyValues = false
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if Trace != nil {
+		return traceEval(node, env)
+	}
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
@@ -22,143 +57,256 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalProgram(node.Statements, env)
 
 	case *ast.ExpressionStatement:
+		// A named function in statement position declares it in the current scope
+		if fl, ok := node.Expression.(*ast.FunctionLiteral); ok && fl.Name != "" {
+			return env.Set(fl.Name, evalFunctionLiteral(fl, env))
+		}
 		return Eval(node.Expression, env)
 
 	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
+		nameFunction(node, val)
 		return env.Set(node.Name.Value, val)
 
+	case *ast.DestructuringStatement:
+		return withPosition(evalDestructuringStatement(node, env), node.Token)
+
 	// Expressions
 
 	case *ast.HashLiteral:
-		pairs := make(map[object.HashKey]object.HashPair)
-		for key, value := range node.Pairs {
-			keyObj := Eval(key, env)
-			if isError(keyObj) {
-				return keyObj
-			}
-			hashableKey, ok := keyObj.(object.Hashable)
-			if !ok {
-				return newError("Can't use expression of type %v as hash key", keyObj.Type())
-			}
-
-			valObj := Eval(value, env)
-			if isError(valObj) {
-				return valObj
-			}
-
-			pairs[hashableKey.HashKey()] = object.HashPair{
-				Value: valObj,
-				Key:   keyObj,
-			}
-		}
-
-		return &object.Hash{
-			Pairs: pairs,
-		}
+		return withPosition(evalHashLiteral(node, env), node.Token)
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.ComparisonChain:
+		return withPosition(evalComparisonChain(node, env), node.Token)
+
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.FunctionLiteral:
-		return &object.Function{
-			Parameters: node.Parameters,
-			Body:       node.Body,
-			Env:        env,
-		}
+		return evalFunctionLiteral(node, env)
 
 	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			if len(node.Arguments) != 1 {
+				return newError("wrong number of arguments. got=%v, want=1)", len(node.Arguments))
+			}
+			return quote(node.Arguments[0], env)
+		}
+
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isAbrupt(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, err := evalExpressions(node.Arguments, env)
+		if err != nil {
+			return err
+		}
+
+		if builtin, ok := function.(*object.Builtin); ok && builtin.EnvFn != nil {
+			return withPosition(builtin.EnvFn(env, args...), node.Token)
+		}
+		if _, ok := function.(*object.Function); !ok {
+			return withPosition(applyFunction(function, args), node.Token)
 		}
 
-		return applyFunction(function, args)
+		result := withPosition(applyFunction(function, args), node.Token)
+		if err, ok := result.(*object.Error); ok {
+			addFrame(err, calleeName(node.Function, function.(*object.Function)), node.Token)
+		}
+		return result
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
-			return elements[0]
+		elements, err := evalExpressions(node.Elements, env)
+		if err != nil {
+			return err
 		}
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isAbrupt(index) {
 			return index
 		}
 
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), node.Token)
+
+	case *ast.SliceExpression:
+		return withPosition(evalSliceExpression(node, env), node.Token)
+
+	case *ast.AssignExpression:
+		return withPosition(evalAssignExpression(node, env), node.Token)
+
+	case *ast.PostfixExpression:
+		return withPosition(evalPostfixExpression(node, env), node.Token)
 
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return withPosition(evalIdentifier(node, env), node.Token)
 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.TernaryExpression:
+		condition := Eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
+
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
+	case *ast.DoExpression:
+		result := evalBlockStatement(node.Body, object.NewEnclosedEnvironment(env))
+		if result == nil {
+			return NULL
+		}
+		return result
+
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return newInteger(node.Value)
+
+	case *ast.BigIntegerLiteral:
+		return &object.BigInt{Value: node.Value}
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 
 	case *ast.BooleanExpression:
 		return nativeBoolToBooleanObject(node.Value)
 
+	case *ast.NullLiteral:
+		return NULL
+
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPosition(evalPrefixExpression(node.Operator, right), node.Token)
 
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
-		right := Eval(node.Right, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
-		if isError(right) {
+		right := Eval(node.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(evalInfixExpression(node.Operator, left, right), node.Token)
 	default:
 		return nil
 	}
 }
 
+// evalSliceExpression copies part of an array or string, with the same
+// rules for the bounds as the `slice` builtin. A missing low bound is the
+// start and a missing high bound the end
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	bounds := []int64{0}
+	for i, bound := range []ast.Expression{node.Low, node.High} {
+		if bound == nil {
+			continue
+		}
+		val := Eval(bound, env)
+		if isAbrupt(val) {
+			return val
+		}
+		integer, ok := indexValue(val)
+		if !ok {
+			return newError("slice index must be INTEGER, got %v", val.Type())
+		}
+		if i == 0 {
+			bounds[0] = integer
+		} else {
+			bounds = append(bounds, integer)
+		}
+	}
+
+	if result := sliceValue(left, bounds); result != nil {
+		return result
+	}
+	return newError("slice operator not supported: %v", left.Type())
+}
+
 func evalIndexExpression(left object.Object, index object.Object) object.Object {
 	switch left := left.(type) {
 	case *object.Array:
-		idx := index.(*object.Integer).Value
-		max := int64(len(left.Elements) - 1)
-		if idx < 0 || idx > max {
+		idx, ok := indexValue(index)
+		if !ok {
+			return newError("array index must be INTEGER, got %v", index.Type())
+		}
+		length := int64(len(left.Elements))
+		if idx < 0 {
+			idx += length
+		}
+		if idx < 0 || idx >= length {
 			return NULL
 		}
 
 		return left.Elements[idx]
 
+	case *object.String:
+		i, ok := indexValue(index)
+		if !ok {
+			return newError("string index must be INTEGER, got %v", index.Type())
+		}
+		runes := []rune(left.Value)
+		if i < 0 {
+			i += int64(len(runes))
+		}
+		if i < 0 || i >= int64(len(runes)) {
+			return NULL
+		}
+
+		return &object.String{Value: string(runes[i])}
+
 	case *object.Hash:
-		key, ok := index.(object.Hashable)
+		key, ok := object.AsHashable(index)
 		if !ok {
 			return newError("unusable as hash key: %v", index.Type())
 		}
@@ -173,21 +321,302 @@ func evalIndexExpression(left object.Object, index object.Object) object.Object
 	}
 }
 
+func evalDestructuringStatement(node *ast.DestructuringStatement, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	if node.Hash {
+		return destructureHash(node.Names, val, env)
+	}
+
+	arr, ok := val.(*object.Array)
+	if !ok {
+		return newError("can't destructure %v as an array", val.Type())
+	}
+	if len(arr.Elements) != len(node.Names) {
+		return newError("wrong number of values to destructure. got=%v, want=%v", len(arr.Elements), len(node.Names))
+	}
+
+	for i, name := range node.Names {
+		env.Set(name.Value, arr.Elements[i])
+	}
+	return val
+}
+
+func destructureHash(names []*ast.Identifier, val object.Object, env *object.Environment) object.Object {
+	hash, ok := val.(*object.Hash)
+	if !ok {
+		return newError("can't destructure %v as a hash", val.Type())
+	}
+
+	// Check every key before binding any, so a failed statement leaves the
+	// environment as it was
+	values := make([]object.Object, len(names))
+	for i, name := range names {
+		pair, ok := hash.Pairs[(&object.String{Value: name.Value}).HashKey()]
+		if !ok {
+			return newError("key not found: %v", name.Value)
+		}
+		values[i] = pair.Value
+	}
+
+	for i, name := range names {
+		env.Set(name.Value, values[i])
+	}
+	return val
+}
+
+// nameFunction names an anonymous function literal after the let that binds
+// it. Functions bound from anything else, like another variable, keep their
+// name
+func nameFunction(let *ast.LetStatement, val object.Object) {
+	if _, ok := let.Value.(*ast.FunctionLiteral); !ok {
+		return
+	}
+	if function, ok := val.(*object.Function); ok && function.Name == "" {
+		function.Name = let.Name.Value
+	}
+}
+
+func evalFunctionLiteral(node *ast.FunctionLiteral, env *object.Environment) object.Object {
+	function := &object.Function{
+		Name:       node.Name,
+		Parameters: node.Parameters,
+		Defaults:   node.Defaults,
+		Rest:       node.Rest,
+		Body:       node.Body,
+		Env:        env,
+	}
+	env.Capture()
+
+	// Named functions can refer to themselves regardless of where they're bound
+	if node.Name != "" {
+		function.Env = object.NewEnclosedEnvironment(env)
+		function.Env.Set(node.Name, function)
+	}
+
+	return function
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	if ident, ok := node.Target.(*ast.Identifier); ok {
+		val := Eval(node.Value, env)
+		if isAbrupt(val) {
+			return val
+		}
+		if _, ok := env.Assign(ident.Value, val); !ok {
+			return newError("identifier not found: " + ident.Value)
+		}
+		return val
+	}
+
+	target := node.Target.(*ast.IndexExpression)
+
+	left := Eval(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	index := Eval(target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+	val := Eval(node.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	return assignIndex(left, index, val)
+}
+
+func assignIndex(left object.Object, index object.Object, val object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := indexValue(index)
+		if !ok {
+			return newError("array index must be INTEGER, got %v", index.Type())
+		}
+		// Negative indexes count from the end, like they do when reading
+		length := int64(len(left.Elements))
+		if idx < 0 {
+			idx += length
+		}
+		if idx < 0 || idx >= length {
+			return newError("index out of range: %v (length %v)", index.Inspect(), length)
+		}
+		left.Elements[idx] = val
+
+	case *object.Hash:
+		key, ok := object.AsHashable(index)
+		if !ok {
+			return newError("unusable as hash key: %v", index.Type())
+		}
+		left.Set(key.HashKey(), object.HashPair{Key: index, Value: val})
+
+	default:
+		return newError("index assignment not supported: %v", left.Type())
+	}
+
+	return val
+}
+
+// evalPostfixExpression adds or subtracts one from an integer variable or
+// element and returns its value from before the change
+func evalPostfixExpression(node *ast.PostfixExpression, env *object.Environment) object.Object {
+	delta := int64(1)
+	if node.Operator == "--" {
+		delta = -1
+	}
+
+	step := func(current object.Object) (object.Object, object.Object) {
+		if current.Type() != object.INTEGER_OBJ {
+			return nil, newError("unknown operator: %v%v", current.Type(), node.Operator)
+		}
+		updated := evalInfixIntegerExpression("+", current, newInteger(delta))
+		if isError(updated) {
+			return nil, updated
+		}
+		return updated, nil
+	}
+
+	if ident, ok := node.Target.(*ast.Identifier); ok {
+		current, ok := env.Get(ident.Value)
+		if !ok {
+			return newError("identifier not found: " + ident.Value)
+		}
+		updated, err := step(current)
+		if err != nil {
+			return err
+		}
+		env.Assign(ident.Value, updated)
+		return current
+	}
+
+	target := node.Target.(*ast.IndexExpression)
+
+	left := Eval(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	index := Eval(target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+
+	current := evalIndexExpression(left, index)
+	if isError(current) {
+		return current
+	}
+	updated, err := step(current)
+	if err != nil {
+		return err
+	}
+	if result := assignIndex(left, index, updated); isError(result) {
+		return result
+	}
+	return current
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch function := fn.(type) {
 	case *object.Function:
-		callEnv := object.NewEnclosedEnvironment(function.Env)
-		for i, arg := range args {
-			callEnv.Set(function.Parameters[i].Value, arg)
+		defer function.Env.LeaveCall()
+		if function.Env.EnterCall() > MaxCallDepth {
+			return newError("maximum recursion depth exceeded")
+		}
+
+		for {
+			// Every iteration gets a fresh environment, so closures created by
+			// earlier iterations keep the arguments they saw
+			callEnv, err := extendFunctionEnv(function, args)
+			if err != nil {
+				return functionResult(err)
+			}
+			result, tail := evalTailBlock(function.Body, callEnv, function)
+			// Unless a closure captured it, nothing can refer to the call's
+			// environment anymore
+			callEnv.Release()
+			if tail != nil {
+				args = tail.args
+				continue
+			}
+			return functionResult(result)
 		}
-		return unwrapReturnValue(evalBlockStatement(function.Body, callEnv))
 	case *object.Builtin:
+		// There's no environment to hand builtins that need one, like eval,
+		// when they're called from Go rather than from a call expression
+		if function.Fn == nil {
+			return newError("builtin function can only be called directly")
+		}
 		return function.Fn(args...)
 	default:
 		return newError("not a function: %v", fn.Type())
 	}
 }
 
+// requiredArguments returns how many arguments function has to be called
+// with, not counting parameters with defaults and rest parameters
+func requiredArguments(function *object.Function) int {
+	required := 0
+	for i := range function.Parameters {
+		if i >= len(function.Defaults) || function.Defaults[i] == nil {
+			required = i + 1
+		}
+	}
+	return required
+}
+
+func extendFunctionEnv(function *object.Function, args []object.Object) (*object.Environment, object.Object) {
+	required := requiredArguments(function)
+
+	variadic := function.Rest != nil
+	if len(args) < required || (!variadic && len(args) > len(function.Parameters)) {
+		want := fmt.Sprint(len(function.Parameters))
+		if variadic {
+			want = fmt.Sprintf("%v+", required)
+		} else if required < len(function.Parameters) {
+			want = fmt.Sprintf("%v..%v", required, len(function.Parameters))
+		}
+		return nil, newError("wrong number of arguments. got=%v, want=%v)", len(args), want)
+	}
+
+	env := object.NewPooledEnvironment(function.Env)
+	for i, param := range function.Parameters {
+		if i < len(args) {
+			env.Set(param.Value, args[i])
+			continue
+		}
+
+		// Defaults are evaluated on every call and can refer to earlier parameters
+		val := Eval(function.Defaults[i], env)
+		if isAbrupt(val) {
+			return nil, val
+		}
+		env.Set(param.Value, val)
+	}
+
+	if variadic {
+		rest := []object.Object{}
+		if len(args) > len(function.Parameters) {
+			rest = append(rest, args[len(function.Parameters):]...)
+		}
+		env.Set(function.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
+}
+
+// functionResult turns whatever ended a call, such as a return, into the
+// value of the call
+func functionResult(result object.Object) object.Object {
+	switch result.(type) {
+	case *object.Break, *object.Continue:
+		return newError("%v outside of a loop", result.Inspect())
+	}
+	return unwrapReturnValue(result)
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if retVal, ok := obj.(*object.ReturnValue); ok {
 		return retVal.Value
@@ -195,37 +624,88 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evalExpressions(nodes []ast.Expression, env *object.Environment) []object.Object {
-	objects := []object.Object{}
+// evalExpressions evaluates nodes in order, stopping at the first error,
+// return, break or continue. That is returned on its own, without the values evaluated before it
+func evalExpressions(nodes []ast.Expression, env *object.Environment) ([]object.Object, object.Object) {
+	objects := make([]object.Object, 0, len(nodes))
 
 	for _, node := range nodes {
 		obj := Eval(node, env)
-		if isError(obj) {
-			return []object.Object{obj}
+		if isAbrupt(obj) {
+			return nil, obj
 		}
 		objects = append(objects, obj)
 	}
 
-	return objects
+	return objects, nil
+}
+
+// evalHashLiteral evaluates keys and values in source order, stopping at the
+// first error. The hash is only built once every pair has been evaluated
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := node.OrderedPairs()
+	keys := make([]object.Object, len(pairs))
+	values := make([]object.Object, len(pairs))
+
+	for i, pair := range pairs {
+		keyObj := Eval(pair.Key, env)
+		if isAbrupt(keyObj) {
+			return keyObj
+		}
+		if _, ok := object.AsHashable(keyObj); !ok {
+			return newError("Can't use expression of type %v as hash key", keyObj.Type())
+		}
+
+		valObj := Eval(pair.Value, env)
+		if isAbrupt(valObj) {
+			return valObj
+		}
+
+		keys[i] = keyObj
+		values[i] = valObj
+	}
+
+	hash := object.NewHash()
+	for i, key := range keys {
+		hashable, _ := object.AsHashable(key)
+		hash.Set(hashable.HashKey(), object.HashPair{Key: key, Value: values[i]})
+	}
+	return hash
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
 
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
 
-	if !ok {
-		return newError("identifier not found: " + node.Value)
-	}
+	return newError("identifier not found: " + node.Value)
+}
 
-	return val
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var buf strings.Builder
+	for _, part := range node.Parts {
+		val := Eval(part, env)
+		if isAbrupt(val) {
+			return val
+		}
+		if str, ok := val.(*object.String); ok {
+			buf.WriteString(str.Value)
+		} else {
+			buf.WriteString(val.Inspect())
+		}
+	}
+	return &object.String{Value: buf.String()}
 }
 
+// evalIfExpression returns NULL when the condition is falsy and there's no
+// else branch, so `let x = if (false) { 1 };` binds x to null
 func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(node.Condition, env)
-	if isError(condition) {
+	if isAbrupt(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
@@ -236,21 +716,116 @@ func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Ob
 	return NULL
 }
 
+// evalMatchExpression returns NULL if no arm matches the subject
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isAbrupt(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		if !arm.IsWildcard() {
+			pattern := Eval(arm.Pattern, env)
+			if isAbrupt(pattern) {
+				return pattern
+			}
+			if subject.Type() != pattern.Type() {
+				continue
+			}
+			if cmp, err := object.Compare(subject, pattern); err != nil || cmp != 0 {
+				continue
+			}
+		}
+		return Eval(arm.Value, env)
+	}
+
+	return NULL
+}
+
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	if node.Init != nil {
+		init := Eval(node.Init, loopEnv)
+		if isAbrupt(init) {
+			return init
+		}
+	}
+
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, loopEnv)
+			if isAbrupt(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				break
+			}
+		}
+
+		result := Eval(node.Body, loopEnv)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			case object.BREAK_OBJ:
+				return NULL
+			}
+		}
+
+		if node.Post != nil {
+			post := Eval(node.Post, loopEnv)
+			if isAbrupt(post) {
+				return post
+			}
+		}
+	}
+
+	return NULL
+}
+
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(node.Body, env)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			case object.BREAK_OBJ:
+				return NULL
+			}
+		}
+	}
+}
+
 func evalInfixExpression(op string, left object.Object, right object.Object) object.Object {
 	switch {
+	case isFloatOperation(left, right):
+		return evalInfixFloatExpression(op, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %v %v %v", left.Type(), op, right.Type())
+	case op == "<" || op == ">":
+		return evalComparison(op, left, right)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalInfixIntegerExpression(op, left, right)
 
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalInfixStringExpression(op, left, right)
 
-	// After here at least one of the operands is a bool
+	// After here the operands are of the same type, which only matters for
+	// equality
 	case op == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equals(left, right))
 	case op == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equals(left, right))
 	default:
 		return newError("unknown operator: %v %v %v", left.Type(), op, right.Type())
 	}
@@ -262,6 +837,8 @@ func evalPrefixExpression(op string, right object.Object) object.Object {
 		return evalBangPrefixOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "+":
+		return evalPlusPrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %v %v", op, right.Type())
 	}
@@ -280,63 +857,230 @@ func evalInfixStringExpression(op string, left object.Object, right object.Objec
 }
 
 func evalInfixIntegerExpression(op string, left object.Object, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	leftInt, leftOk := left.(*object.Integer)
+	rightInt, rightOk := right.(*object.Integer)
+	if !leftOk || !rightOk {
+		return evalBigIntegerExpression(op, left, right)
+	}
+	leftVal, rightVal := leftInt.Value, rightInt.Value
 
+	var result int64
+	var ok bool
 	switch op {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		result, ok = addInt64(leftVal, rightVal)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		result, ok = subInt64(leftVal, rightVal)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		result, ok = mulInt64(leftVal, rightVal)
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		result, ok = leftVal/rightVal, leftVal != math.MinInt64 || rightVal != -1
+	case "**":
+		return power(leftVal, rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
-	case ">":
-		return nativeBoolToBooleanObject(leftVal > rightVal)
-	case "<":
-		return nativeBoolToBooleanObject(leftVal < rightVal)
 	default:
 		return newError("unknown operator: %v %v %v",
 			left.Type(), op, right.Type())
 	}
+
+	if !ok && BigIntegers {
+		return evalBigIntegerExpression(op, left, right)
+	}
+	return checkedInteger(result, ok)
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%v", right.Type())
+// isFloatOperation reports whether left and right are numbers with at least
+// one of them a float, in which case arithmetic is done on floats
+func isFloatOperation(left object.Object, right object.Object) bool {
+	isNumber := func(obj object.Object) bool {
+		return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
 	}
-	integer := right.(*object.Integer)
-	return &object.Integer{Value: -integer.Value}
+	return isNumber(left) && isNumber(right) &&
+		(left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ)
 }
 
-func evalBangPrefixOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
+// evalInfixFloatExpression does arithmetic and comparisons on floats, and on
+// integers mixed with floats, which are converted to floats first
+func evalInfixFloatExpression(op string, left object.Object, right object.Object) object.Object {
+	leftVal, rightVal := floatValue(left), floatValue(right)
+
+	switch op {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<", ">":
+		return evalComparison(op, left, right)
 	default:
-		return FALSE
+		return newError("unknown operator: %v %v %v",
+			left.Type(), op, right.Type())
+	}
+}
+
+// floatValue converts an Integer, BigInt or Float to a float
+func floatValue(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		return bigFloat(obj)
+	}
+	return obj.(*object.Float).Value
+}
+
+func evalComparisonChain(node *ast.ComparisonChain, env *object.Environment) object.Object {
+	left := Eval(node.Operands[0], env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	for i, op := range node.Operators {
+		right := Eval(node.Operands[i+1], env)
+		if isAbrupt(right) {
+			return right
+		}
+
+		result := evalInfixExpression(op, left, right)
+		if b, ok := result.(*object.Boolean); !ok || !b.Value {
+			return result
+		}
+		left = right
+	}
+
+	return TRUE
+}
+
+func evalComparison(op string, left object.Object, right object.Object) object.Object {
+	cmp, err := object.Compare(left, right)
+	if err != nil {
+		return newError("unknown operator: %v %v %v", left.Type(), op, right.Type())
+	}
+	if op == "<" {
+		return nativeBoolToBooleanObject(cmp < 0)
 	}
+	return nativeBoolToBooleanObject(cmp > 0)
+}
+
+func power(base int64, exp int64) object.Object {
+	if exp < 0 {
+		return newError("negative exponent not supported: %v", exp)
+	}
+
+	result, ok := exactPower(base, exp)
+	if !ok && BigIntegers {
+		return bigPower(big.NewInt(base), exp)
+	}
+	if !ok && CheckOverflow {
+		return newError("integer overflow")
+	}
+	return newInteger(result)
+}
+
+// exactPower returns the wrapped-around base ** exp and whether it's exact
+func exactPower(base int64, exp int64) (int64, bool) {
+	result, exact := int64(1), true
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			result, ok = mulInt64(result, base)
+			exact = exact && ok
+		}
+		exp >>= 1
+		if exp == 0 {
+			break
+		}
+		base, ok = mulInt64(base, base)
+		exact = exact && ok
+	}
+	return result, exact
+}
+
+// checkedInteger wraps the result of addInt64, subInt64 or mulInt64
+func checkedInteger(value int64, ok bool) object.Object {
+	if !ok && CheckOverflow {
+		return newError("integer overflow")
+	}
+	return newInteger(value)
+}
+
+// addInt64, subInt64 and mulInt64 return the wrapped-around result and
+// whether it's exact
+func addInt64(a, b int64) (int64, bool) {
+	sum := a + b
+	return sum, (sum > a) == (b > 0)
+}
+
+func subInt64(a, b int64) (int64, bool) {
+	diff := a - b
+	return diff, (diff < a) == (b > 0)
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	product := a * b
+	if a == 0 || b == 0 {
+		return product, true
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return product, false
+	}
+	return product, product/b == a
+}
+
+func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 && BigIntegers {
+			return newBigInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return checkedInteger(-right.Value, right.Value != math.MinInt64)
+	case *object.BigInt:
+		return newBigInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	}
+	return newError("unknown operator: -%v", right.Type())
+}
+
+// evalPlusPrefixOperatorExpression returns numbers unchanged
+func evalPlusPrefixOperatorExpression(right object.Object) object.Object {
+	switch right.(type) {
+	case *object.Integer, *object.BigInt, *object.Float:
+		return right
+	}
+	return newError("unknown operator: +%v", right.Type())
+}
+
+func evalBangPrefixOperatorExpression(right object.Object) object.Object {
+	return nativeBoolToBooleanObject(!isTruthy(right))
 }
 
 func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
-	for _, statement := range statements {
-		result = Eval(statement, env)
+	hoistFunctions(statements, env)
 
-		switch result := result.(type) {
-		case *object.ReturnValue:
-			return result.Value
-		case *object.Error:
+	for _, statement := range statements {
+		var done bool
+		if result, done = evalProgramStatement(statement, env); done {
 			return result
 		}
 	}
@@ -344,23 +1088,80 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 	return result
 }
 
+// hoistFunctions binds the functions defined by top-level lets before the
+// program runs, so functions can call ones defined after them, like
+// mutually recursive pairs, even if they're called before both are defined.
+// Evaluating a function literal has no side effects, so the let still
+// rebinds the name when it's reached
+func hoistFunctions(statements []ast.Statement, env *object.Environment) {
+	for _, statement := range statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		if function, ok := let.Value.(*ast.FunctionLiteral); ok {
+			val := evalFunctionLiteral(function, env)
+			nameFunction(let, val)
+			env.Set(let.Name.Value, val)
+		}
+	}
+}
+
+// evalProgramStatement evaluates a top-level statement and reports whether
+// the program ends with it
+func evalProgramStatement(statement ast.Statement, env *object.Environment) (object.Object, bool) {
+	result := Eval(statement, env)
+
+	switch result := result.(type) {
+	case *object.ReturnValue:
+		return result.Value, true
+	case *object.Error:
+		return result, true
+	case *object.Break, *object.Continue:
+		return newError("%v outside of a loop", result.Inspect()), true
+	}
+
+	return result, false
+}
+
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil && result.Type() == object.RETURN_VALUE_OBJ {
-			return result
-		}
-
-		if result != nil && result.Type() == object.ERROR_OBJ {
-			return result
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+				return result
+			}
 		}
 	}
 
 	return result
 }
 
+// Small integers are interned like TRUE and FALSE, so arithmetic in tight
+// loops doesn't allocate a new object for every intermediate result
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 255
+)
+
+var cachedIntegers = func() []*object.Integer {
+	integers := make([]*object.Integer, maxCachedInteger-minCachedInteger+1)
+	for i := range integers {
+		integers[i] = &object.Integer{Value: int64(i + minCachedInteger)}
+	}
+	return integers
+}()
+
+func newInteger(val int64) *object.Integer {
+	if val >= minCachedInteger && val <= maxCachedInteger {
+		return cachedIntegers[val-minCachedInteger]
+	}
+	return &object.Integer{Value: val}
+}
+
 func nativeBoolToBooleanObject(val bool) *object.Boolean {
 	if val {
 		return TRUE
@@ -369,18 +1170,65 @@ func nativeBoolToBooleanObject(val bool) *object.Boolean {
 }
 
 func isTruthy(obj object.Object) bool {
-	if obj == FALSE || obj == NULL {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
 		return false
 	}
 
+	if FalsyEmptyValues {
+		switch obj := obj.(type) {
+		case *object.Integer:
+			return obj.Value != 0
+		case *object.Float:
+			return obj.Value != 0
+		case *object.String:
+			return obj.Value != ""
+		case *object.Array:
+			return len(obj.Elements) != 0
+		case *object.Hash:
+			return len(obj.Pairs) != 0
+		}
+	}
+
 	return true
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-	err := &object.Error{
-		Message: fmt.Sprintf(format, a...),
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// calleeName prefers the name a function is called by over its own name
+func calleeName(callee ast.Expression, function *object.Function) string {
+	if ident, ok := callee.(*ast.Identifier); ok {
+		return ident.Value
+	}
+	if function.Name != "" {
+		return function.Name
+	}
+	return "<anonymous>"
+}
+
+// addFrame records the call of a Monkey function an error passed through on
+// its way out, so errors show how they came about. Frames are added
+// innermost first, and those past maxStackFrames are only counted
+func addFrame(err *object.Error, name string, tok token.Token) {
+	if len(err.Stack) >= maxStackFrames {
+		err.MoreFrames++
+		return
 	}
-	return err
+	err.Stack = append(err.Stack, fmt.Sprintf("%v (%d:%d)", name, tok.Line, tok.Column))
+}
+
+// withPosition attaches the position of tok to errors that don't carry one yet,
+// so errors report the innermost node they originated from
+func withPosition(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.Line = tok.Line
+		err.Column = tok.Column
+	}
+	return obj
 }
 
 func isError(obj object.Object) bool {
@@ -389,3 +1237,17 @@ func isError(obj object.Object) bool {
 	}
 	return false
 }
+
+// isAbrupt reports whether evaluating a subexpression cut evaluation short,
+// either with an error or with a return, break or continue from inside a
+// `do` expression, which has to propagate like an error does
+func isAbrupt(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	}
+	return false
+}
@@ -1,8 +1,20 @@
 package evaluator
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"monkey-interpreter/ast"
 	"monkey-interpreter/lexer"
 	"monkey-interpreter/object"
 	"monkey-interpreter/parser"
@@ -17,6 +29,11 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"10", 10},
 		{"-10", -10},
 		{"-20", -20},
+		{"+5", 5},
+		{"+-5", -5},
+		{"-+5", -5},
+		{"3 - +2", 1},
+		{"+(2 * 3)", 6},
 		{"5 + 5 + 5 + 5 - 10", 10},
 		{"2 * 2 * 2 * 2 * 2", 32},
 		{"-50 + 100 + -50", 0},
@@ -28,6 +45,10 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"2 * 3 ** 2", 18},
+		{"-2 ** 2", 4},
 	}
 
 	for _, tt := range tests {
@@ -36,6 +57,171 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerOverflow(t *testing.T) {
+	const max, min = "9223372036854775807", "(-9223372036854775807 - 1)"
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{max, int64(math.MaxInt64)},
+		{min, int64(math.MinInt64)},
+		{max + " + 0", int64(math.MaxInt64)},
+		{max + " - 1 + 1", int64(math.MaxInt64)},
+		{min + " + " + max, int64(-1)},
+		{min + " * 1", int64(math.MinInt64)},
+		{"-" + max + " * -1", int64(math.MaxInt64)},
+		{"2 ** 62", int64(1 << 62)},
+		{"-2 ** 63", int64(math.MinInt64)},
+		{"3037000499 * 3037000499", int64(9223372030926249001)},
+		{max + " + 1", errorMessage("integer overflow")},
+		{min + " - 1", errorMessage("integer overflow")},
+		{"1 - " + min, errorMessage("integer overflow")},
+		{min + " + -1", errorMessage("integer overflow")},
+		{max + " * 2", errorMessage("integer overflow")},
+		{min + " * -1", errorMessage("integer overflow")},
+		{"-1 * " + min, errorMessage("integer overflow")},
+		{"3037000500 * 3037000500", errorMessage("integer overflow")},
+		{"-" + min, errorMessage("integer overflow")},
+		{"2 ** 63", errorMessage("integer overflow")},
+		{"10 ** 19", errorMessage("integer overflow")},
+		{min + " / -1", errorMessage("integer overflow")},
+		{"1 / 0", errorMessage("division by zero")},
+		{"let x = " + max + "; x--; x", int64(math.MaxInt64 - 1)},
+		{"let x = " + max + "; x++", errorMessage("integer overflow")},
+		{"let x = " + min + "; x--", errorMessage("integer overflow")},
+		{"let a = [" + max + "]; a[0]++", errorMessage("integer overflow")},
+		{"let x = " + max + "; let y = try(fn() { x++ }, fn(e) { 0 }); x", int64(math.MaxInt64)},
+		{"abs(" + min + " + 1)", int64(math.MaxInt64)},
+		{"abs(" + min + ")", errorMessage("integer overflow")},
+		{"sum([" + max + ", -1, 1])", int64(math.MaxInt64)},
+		{"sum([" + max + ", 1])", errorMessage("integer overflow")},
+		{"sum([" + min + ", -1])", errorMessage("integer overflow")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+
+	defer func(check bool) { CheckOverflow = check }(CheckOverflow)
+	CheckOverflow = false
+
+	testIntegerObject(t, testEval(max+" + 1"), math.MinInt64)
+	testIntegerObject(t, testEval(min+" * -1"), math.MinInt64)
+	testIntegerObject(t, testEval(min+" / -1"), math.MinInt64)
+	testIntegerObject(t, testEval("2 ** 64"), 0)
+}
+
+func TestBigIntegers(t *testing.T) {
+	defer func(big bool) { BigIntegers = big }(BigIntegers)
+	BigIntegers = true
+
+	factorial := "let factorial = fn(n) { if (n < 2) { 1 } else { n * factorial(n - 1) } };"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{factorial + "factorial(30)", "265252859812191058636308480000000"},
+		{factorial + "factorial(20)", "2432902008176640000"},
+		{factorial + "factorial(30) / factorial(28)", "870"},
+		{factorial + "type(factorial(25))", `"INTEGER"`},
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"2 ** 64", "18446744073709551616"},
+		{"-(2 ** 64)", "-18446744073709551616"},
+		{"2 ** 100 / 2 ** 98", "4"},
+		{"(2 ** 64) ** 2", "340282366920938463463374607431768211456"},
+		{"-7 * 2 ** 64 / 2 ** 65", "-3"},
+		{"2 ** 64 - 2 ** 64 + 1", "1"},
+		{"2 ** 64 > 9223372036854775807", "true"},
+		{"-(2 ** 64) < -9223372036854775807", "true"},
+		{"2 ** 64 == 2 ** 64", "true"},
+		{"2 ** 64 == 2 ** 64 + 1", "false"},
+		{"2 ** 64 != 2 ** 63 * 2", "false"},
+		{`{2 ** 64: "big"}[2 ** 65 / 2]`, `"big"`},
+		{`{0: "zero"}[2 ** 64 - 2 ** 64]`, `"zero"`},
+		{"len(unique([2 ** 64, 2 ** 63 * 2, 2 ** 64 + 1]))", "2"},
+		{"sort([2 ** 64, 1, -(2 ** 70)])", "[-1180591620717411303424, 1, 18446744073709551616]"},
+		{"json([2 ** 64])", `"[18446744073709551616]"`},
+		{"2 ** 64 / 0", "ERROR: 1:9: division by zero"},
+		{"2 ** (2 ** 64)", "ERROR: 1:3: integer too large"},
+		{"10 ** 1000000", "ERROR: 1:4: integer too large"},
+		{"2 ** 64 ** 0", "2"},
+		{"(2 ** 64) ** -1", "ERROR: 1:11: negative exponent not supported: -1"},
+		{"2 ** 64 + true", "ERROR: 1:9: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 9223372036854775807; x++; x", "9223372036854775808"},
+		{"let x = -(2 ** 64); x++; x", "-18446744073709551615"},
+		{"let a = [2 ** 64]; a[0]--; a", "[18446744073709551615]"},
+		{"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"abs(-(2 ** 64))", "18446744073709551616"},
+		{"sum([9223372036854775807, 1])", "9223372036854775808"},
+		{"sum([9223372036854775807, 1, -2])", "9223372036854775806"},
+		{"sum([2 ** 64, 2 ** 64])", "36893488147419103232"},
+		{"min(2 ** 64, 5, -(2 ** 65))", "-36893488147419103232"},
+		{"max([2 ** 64, 5])", "18446744073709551616"},
+		{"min(2 ** 64, true)", "ERROR: 1:4: argument to `min` not supported, got BOOLEAN"},
+		{"pow(2 ** 64, 2)", "340282366920938463463374607431768211456"},
+		{"pow(2, 2 ** 64)", "ERROR: 1:4: integer too large"},
+		{"rand(2 ** 64) < 2 ** 64", "true"},
+		{"rand(-(2 ** 64))", "ERROR: 1:5: argument to `rand` must be positive, got -18446744073709551616"},
+		{"round(2 ** 64)", "18446744073709551616"},
+		{"floor(-(2 ** 64))", "-18446744073709551616"},
+		{"round(sqrt(2 ** 64))", "4294967296"},
+		{"[1, 2, 3][2 ** 64]", "null"},
+		{"[1, 2, 3][-(2 ** 64)]", "null"},
+		{`"abc"[2 ** 64]`, "null"},
+		{"[1, 2, 3][-(2 ** 64):2 ** 64]", "[1, 2, 3]"},
+		{"slice([1, 2, 3], 1, 2 ** 64)", "[2, 3]"},
+		{"repeat(\"a\", 2 ** 64)", "ERROR: 1:7: argument to `repeat` out of range, got 18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to evaluate to %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// Results that fit in 64 bits are regular integers again
+	testIntegerObject(t, testEval("2 ** 64 / 2 ** 60"), 16)
+
+	literals := []struct {
+		input    string
+		expected string
+	}{
+		{"18446744073709551616", "18446744073709551616"},
+		{"-18_446_744_073_709_551_616 + 1", "-18446744073709551615"},
+		{"18446744073709551616 - 2 ** 64", "0"},
+		{`eval("18446744073709551616 * 2")`, "36893488147419103232"},
+	}
+
+	for _, tt := range literals {
+		evaluated, errors := Run(tt.input)
+		if len(errors) != 0 {
+			t.Errorf("Expected %q to parse, instead got %v", tt.input, errors)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to evaluate to %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -104,6 +290,12 @@ func TestIfExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"let x = if (false) { 10 }; x", nil},
+		{"if (1 < 2) { 10 } else if (1 < 3) { 20 } else { 30 }", 10},
+		{"if (1 > 2) { 10 } else if (1 < 3) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (1 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (1 > 3) { 20 }", nil},
+		{"let f = fn(x) { if (x < 0) { -1 } else if (x == 0) { 0 } else { 1 } }; f(0)", 0},
 	}
 
 	for _, tt := range tests {
@@ -118,6 +310,55 @@ func TestIfExpressions(t *testing.T) {
 	}
 }
 
+func TestFalsyEmptyValues(t *testing.T) {
+	tests := []struct {
+		input     string
+		byDefault interface{}
+		falsy     interface{}
+	}{
+		{`if (0) { 1 } else { 2 }`, 1, 2},
+		{`if (1) { 1 } else { 2 }`, 1, 1},
+		{`if ("") { 1 } else { 2 }`, 1, 2},
+		{`if ("a") { 1 } else { 2 }`, 1, 1},
+		{`if ([]) { 1 } else { 2 }`, 1, 2},
+		{`if ([0]) { 1 } else { 2 }`, 1, 1},
+		{`if ({}) { 1 } else { 2 }`, 1, 2},
+		{`if ({"a": 1}) { 1 } else { 2 }`, 1, 1},
+		{`if (false) { 1 } else { 2 }`, 2, 2},
+		{`if (null) { 1 } else { 2 }`, 2, 2},
+		{`"" ? 1 : 2`, 1, 2},
+		{`let n = 0; let c = 0; while (n) { n = false; c = 1 }; c`, 1, 0},
+		{`!0`, false, true},
+		{`!""`, false, true},
+		{`![]`, false, true},
+		{`!5`, false, false},
+	}
+
+	defer func(falsy bool) { FalsyEmptyValues = falsy }(FalsyEmptyValues)
+
+	for _, falsy := range []bool{false, true} {
+		FalsyEmptyValues = falsy
+		for _, tt := range tests {
+			expected := tt.byDefault
+			if falsy {
+				expected = tt.falsy
+			}
+
+			evaluated := testEval(tt.input)
+			switch expected := expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			}
+		}
+	}
+
+	FalsyEmptyValues = true
+	evaluated := testEvalJSON("0.0", `if (parseJSON(doc)) { 1 } else { 2 }`)
+	testIntegerObject(t, evaluated, 2)
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -129,6 +370,8 @@ func TestBangOperator(t *testing.T) {
 		{"!!false", false},
 		{"!5", false},
 		{"!!5", true},
+		{"!null", true},
+		{"!!null", false},
 	}
 
 	for _, tt := range tests {
@@ -137,6 +380,82 @@ func TestBangOperator(t *testing.T) {
 	}
 }
 
+func TestBooleansByValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"!isInt(1)", false},
+		{`!startsWith("a", "b")`, true},
+		{"!!isNull(null)", true},
+		{"!f", true},
+		{"!t", false},
+		{"f == false", true},
+		{"t == true", true},
+		{"f != false", false},
+		{"t == f", false},
+		{"if (f) { 1 } else { 2 }", 2},
+		{"t ? 1 : 2", 1},
+		{"let n = 0; while (t) { n = 1; t = false }; n", 1},
+		{"match f { false => 1, _ => 2 }", 1},
+		{"1 < 2 < 3 == t", true},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("t", &object.Boolean{Value: true})
+		env.Set("f", &object.Boolean{Value: false})
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			if testBooleanObject(t, evaluated, expected) && evaluated != nativeBoolToBooleanObject(expected) {
+				t.Errorf("Expected %s to evaluate to the %v singleton", tt.input, expected)
+			}
+		}
+	}
+}
+
+func TestNullLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null", nil},
+		{"let x = null; x", nil},
+		{"null == null", true},
+		{"null != null", false},
+		{"[1, 2][5] == null", true},
+		{"if (null) { 1 } else { 2 }", 2},
+		{"null == 1", errorMessage("type mismatch: NULL == INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case nil:
+			if evaluated != NULL {
+				t.Errorf("Expected NULL for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+			}
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -162,6 +481,31 @@ func TestReturnStatement(t *testing.T) {
 	}
 }
 
+func TestBareReturn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn() { return; }()", nil},
+		{"fn() { return }()", nil},
+		{"let f = fn() { return; 5 }; f()", nil},
+		{"let f = fn(x) { if (x > 1) { return; } x }; f(1)", 1},
+		{"let f = fn(x) { if (x > 1) { return } x }; f(2)", nil},
+		{"let n = 0; let f = fn() { n = 1; return; n = 2 }; f(); n", 1},
+		{"return; 5", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -176,6 +520,14 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"+true",
+			"unknown operator: +BOOLEAN",
+		},
+		{
+			`+"5"`,
+			"unknown operator: +STRING",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -208,6 +560,14 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[1, fn(x) { x }]: 1}`,
+			"Can't use expression of type ARRAY as hash key",
+		},
+		{
+			`{"name": "Monkey"}[[fn(x) { x }]];`,
+			"unusable as hash key: ARRAY",
+		},
 	}
 
 	for _, tt := range tests {
@@ -266,6 +626,29 @@ func TestFunctionObject(t *testing.T) {
 	}
 }
 
+func TestFunctionNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x) { x }", "fn(x){\nx\n}"},
+		{"fn double(x) { x * 2 }", "fn double(x){\n(x * 2)\n}"},
+		{"let add = fn(a, b) { a + b }; add", "fn add(a, b){\n(a + b)\n}"},
+		{"let f = fn g() { 1 }; f", "fn g(){\n1\n}"},
+		{"let f = fn(x) { x }; let g = f; g", "fn f(x){\nx\n}"},
+		{"let make = fn() { fn(x) { x } }; let h = make(); h", "fn(x){\nx\n}"},
+		{"let fns = [fn(...xs) { xs }]; fns[0]", "fn(...xs){\nxs\n}"},
+		{"let compose = fn(f, g) { fn(x) { g(f(x)) } }; compose", "fn compose(f, g){\nfn(x){g(f(x))}\n}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to inspect as %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestStringObject(t *testing.T) {
 	input := `"foobar";`
 	expected := "foobar"
@@ -298,6 +681,122 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestArrayDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b, c] = [1, 2, 3]; a + b * c", 7},
+		{"let pair = fn() { [10, 20] }; let [x, y] = pair(); y - x", 10},
+		{"let [a] = [[1, 2]]; len(a)", 2},
+		{"let a = 1; let f = fn() { let [a, b] = [5, 6]; a }; f() + a", 6},
+		{"let [a, b] = [1, 2, 3]", errorMessage("wrong number of values to destructure. got=3, want=2")},
+		{"let [a, b, c] = [1, 2]", errorMessage("wrong number of values to destructure. got=2, want=3")},
+		{"let [a, b] = [1, 2, 3]; a", errorMessage("wrong number of values to destructure. got=3, want=2")},
+		{"let [a] = 1", errorMessage("can't destructure INTEGER as an array")},
+		{"let [a] = foobar", errorMessage("identifier not found: foobar")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHashDestructuring(t *testing.T) {
+	person := `let person = {"name": "Ann", "age": 30, 1: "one"};`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{person + `let {name, age} = person; name`, "Ann"},
+		{person + `let {age} = person; age + 1`, 31},
+		{person + `let {age, name} = person; age`, 30},
+		{`let {a} = {"a": {"b": 2}}; a["b"]`, 2},
+		{person + `let {name, height} = person`, errorMessage("key not found: height")},
+		{`let {a} = [1]`, errorMessage("can't destructure ARRAY as a hash")},
+		{`let {a} = foobar`, errorMessage("identifier not found: foobar")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New(`let name = "old"; let {name, missing} = {"name": "new"};`)).ParseProgram()
+	Eval(program, env)
+	name, _ := env.Get("name")
+	testStringObject(t, name, "old")
+}
+
+func TestMutualRecursion(t *testing.T) {
+	isEven := "let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };"
+	isOdd := "let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };"
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{isEven + isOdd + "isEven(10)", true},
+		{isOdd + isEven + "isEven(10)", true},
+		{isEven + isOdd + "isOdd(7)", true},
+		{isOdd + isEven + "isOdd(10)", false},
+		{"let r = isEven(4);" + isEven + isOdd + "r", true},
+		{"let r = isOdd(3);" + isOdd + isEven + "r", true},
+		{"let r = f(); let f = fn() { 1 }; let f = fn() { 2 }; r", 2},
+		{"let r = f(); let f = 5; let f = fn() { 1 }; r", 1},
+		{"let r = x; let x = 1; r", errorMessage("identifier not found: x")},
+		{"let f = fn() { g() }; let r = f(); let g = fn() { 1 }; r", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 		let newAdder = fn(x) {
@@ -308,6 +807,64 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestClosuresWithPooledEnvironments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(x) { x * 2 }; let newAdder = fn(x) { fn(y) { x + y } }; let a = newAdder(1); f(10); f(20); a(1)", 2},
+		{"let newAdder = fn(x) { fn(y) { x + y } }; let a = newAdder(1); let b = newAdder(10); a(1) + b(1)", 13},
+		{"let counter = fn() { let n = 0; fn() { n++; n } }; let c = counter(); c(); c(); counter()(); c()", 3},
+		{"let f = fn(x) { for (let i = 0; i < 1; i++) { return fn() { x + i } } }; let g = f(5); f(100); g()", 5},
+		{"let f = fn(x) { do { fn() { x } } }; let g = f(7); f(8); g()", 7},
+		{"let f = fn(x) { eval(\"fn() { x }\") }; let g = f(9); f(10); g()", 9},
+		{"let f = fn(x, g = fn() { x }) { g }; let h = f(4); f(5); h()", 4},
+		{"let f = fn(x) { fn inner() { x } }; let g = f(6); f(1); g()", 6},
+		{"let id = fn(x) { x }; let make = fn(x) { let y = id(x); fn() { y } }; let g = make(3); make(4); id(5); g()", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"sum is ${1 + 2}"`, "sum is 3"},
+		{`let name = "monkey"; "hello, ${name}!"`, "hello, monkey!"},
+		{`"${1}${2}${"three"}"`, "12three"},
+		{`"list: ${[1, 2]}, ok: ${true}"`, "list: [1, 2], ok: true"},
+		{`let f = fn(x) { x * 2 }; "f(2) = ${f(2)}"`, "f(2) = 4"},
+		{`"nested ${"inner ${1 + 1}"}"`, "nested inner 2"},
+		{`"literal \${1 + 2}"`, "literal ${1 + 2}"},
+		{`"no interpolation $ {1}"`, "no interpolation $ {1}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("Expected a String object, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("Expected String value to be %q, instead got %q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"value: ${foobar}"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: foobar" {
+		t.Errorf("Expected error message to be %q, instead got %q", "identifier not found: foobar", errObj.Message)
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -320,18 +877,91 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1)"},
 		{`len([1, 2, 3]);`, 3},
 		{`len([]);`, 0},
+		{`len({"a": 1, "b": 2});`, 2},
+		{`len({});`, 0},
+		{`let h = {"a": 1}; h["a"] = 2; h["b"] = 3; len(h);`, 2},
 		{`head([]);`, nil},
 		{`head([1, 2, 3]);`, 1},
 		{`tail([1, 2, 3]);`, []int64{2, 3}},
 		{`tail([]);`, []int64{}},
-		{`tail([1]);`, []int64{1}},
+		{`tail([1]);`, []int64{}},
 		{`last([]);`, nil},
 		{`last([1]);`, 1},
 		{`last([1, 2, 3]);`, 3},
+		{`head(5);`, "argument to `head` not supported, got INTEGER"},
+		{`tail(5);`, "argument to `tail` not supported, got INTEGER"},
+		{`last(5);`, "argument to `last` not supported, got INTEGER"},
+		{`head([1], [2]);`, "wrong number of arguments. got=2, want=1)"},
+		{`tail();`, "wrong number of arguments. got=0, want=1)"},
+		{`last("abc");`, "argument to `last` not supported, got STRING"},
 		{`push([1, 2], 3);`, []int64{1, 2, 3}},
-		{`push(5);`, "wrong number of arguments. got=1, want=2)"},
+		{`push(5);`, "wrong number of arguments. got=1, want=2+)"},
+		{`push([1]);`, "wrong number of arguments. got=1, want=2+)"},
 		{`push(5, 5);`, "argument to `push` not supported, got INTEGER"},
+		{`push(5, 5, 6);`, "argument to `push` not supported, got INTEGER"},
 		{`push([], 5);`, []int64{5}},
+		{`push([1], 2, 3, 4);`, []int64{1, 2, 3, 4}},
+		{`let a = [1]; push(a, 2, 3); a`, []int64{1}},
+		{`set([1, 2, 3], 1, 9);`, []int64{1, 9, 3}},
+		{`let a = [1, 2, 3]; set(a, 0, 9); a;`, []int64{1, 2, 3}},
+		{`set([1, 2, 3], 5, 9);`, "index out of range: 5 (length 3)"},
+		{`set([1, 2, 3], -1, 9);`, "index out of range: -1 (length 3)"},
+		{`set(1, 0, 9);`, "argument to `set` not supported, got INTEGER"},
+		{`insert([1, 2, 3], 0, 9);`, []int64{9, 1, 2, 3}},
+		{`insert([1, 2, 3], 1, 9);`, []int64{1, 9, 2, 3}},
+		{`insert([1, 2, 3], 3, 9);`, []int64{1, 2, 3, 9}},
+		{`insert([], 0, 9);`, []int64{9}},
+		{`let a = [1, 2]; insert(a, 1, 9); a;`, []int64{1, 2}},
+		{`insert([1, 2, 3], 4, 9);`, "index out of range: 4 (length 3)"},
+		{`insert([1, 2, 3], -1, 9);`, "index out of range: -1 (length 3)"},
+		{`insert([1], "a", 9);`, "index to `insert` must be INTEGER, got STRING"},
+		{`insert(1, 0, 9);`, "argument to `insert` not supported, got INTEGER"},
+		{`insert([1], 0);`, "wrong number of arguments. got=2, want=3)"},
+		{`remove([1, 2, 3], 1);`, []int64{1, 3}},
+		{`remove([1, 2, 3], 0);`, []int64{2, 3}},
+		{`remove([1, 2, 3], 2);`, []int64{1, 2}},
+		{`remove([1], 0);`, []int64{}},
+		{`let a = [1, 2, 3]; remove(a, 1); a;`, []int64{1, 2, 3}},
+		{`remove([1, 2, 3], 3);`, "index out of range: 3 (length 3)"},
+		{`remove([], 0);`, "index out of range: 0 (length 0)"},
+		{`remove([1], true);`, "index to `remove` must be INTEGER, got BOOLEAN"},
+		{`remove("abc", 0);`, "argument to `remove` not supported, got STRING"},
+		{`first([1, 2, 3]);`, 1},
+		{`first([]);`, nil},
+		{`let first = fn(x) { 42 }; first([1]);`, 42},
+		{`rest([1, 2, 3]);`, []int64{2, 3}},
+		{`rest([]);`, []int64{}},
+		{`slice([1, 2, 3, 4], 1, 3);`, []int64{2, 3}},
+		{`slice([1, 2, 3, 4], 1);`, []int64{2, 3, 4}},
+		{`slice([1, 2, 3, 4], -2);`, []int64{3, 4}},
+		{`slice([1, 2, 3, 4], -3, -1);`, []int64{2, 3}},
+		{`slice([1, 2, 3, 4], -10, 10);`, []int64{1, 2, 3, 4}},
+		{`slice([1, 2, 3, 4], 3, 1);`, []int64{}},
+		{`slice([1, 2, 3, 4], 10);`, []int64{}},
+		{`slice(1, 0);`, "argument to `slice` not supported, got INTEGER"},
+		{`slice([1], "a");`, "index to `slice` must be INTEGER, got STRING"},
+		{`slice([1]);`, "wrong number of arguments. got=1, want=2..3)"},
+		{`min(3, 1, 2);`, 1},
+		{`min([3, -1, 2]);`, -1},
+		{`min(5);`, 5},
+		{`min([]);`, "`min` needs at least one value"},
+		{`min(1, "a");`, "argument to `min` not supported, got STRING"},
+		{`max(3, 1, 2);`, 3},
+		{`max([3, -1, 7]);`, 7},
+		{`max();`, "`max` needs at least one value"},
+		{`abs(-5);`, 5},
+		{`abs(5);`, 5},
+		{`abs("a");`, "argument to `abs` not supported, got STRING"},
+		{`sum([1, 2, 3]);`, 6},
+		{`sum([]);`, 0},
+		{`sum([1, true]);`, "argument to `sum` not supported, got BOOLEAN"},
+		{`sum(1);`, "argument to `sum` not supported, got INTEGER"},
+		{`pow(2, 10);`, 1024},
+		{`pow(-3, 3);`, -27},
+		{`pow(7, 0);`, 1},
+		{`pow(2, -1);`, "negative exponent not supported: -1"},
+		{`pow(2, "a");`, "argument to `pow` not supported, got STRING"},
+		{`set([1], 0);`, "wrong number of arguments. got=2, want=3)"},
 	}
 
 	for _, tt := range tests {
@@ -357,6 +987,11 @@ func TestBuiltinFunctions(t *testing.T) {
 				t.Errorf("Expected an array, instead got %T(%+v))", evaluated, evaluated)
 			}
 
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("Expected %v elements, instead got %v", len(expected), len(arr.Elements))
+				continue
+			}
+
 			for i, val := range arr.Elements {
 				testIntegerObject(t, val, expected[i])
 			}
@@ -365,185 +1000,2768 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
-func TestArrayLiterals(t *testing.T) {
-	input := "[1, 2 + 3, 4 * 5];"
-	evaluated := testEval(input)
-
-	arr, ok := evaluated.(*object.Array)
-	if !ok {
-		t.Fatalf("Expected object to be Array, instead got %T", evaluated)
-	}
-
-	if len(arr.Elements) != 3 {
-		t.Fatalf("Expected array to have 3 elements, instead got %v", len(arr.Elements))
-	}
-
-	testIntegerObject(t, arr.Elements[0], 1)
-	testIntegerObject(t, arr.Elements[1], 5)
-	testIntegerObject(t, arr.Elements[2], 20)
-}
-
-func TestArrayIndexExpressions(t *testing.T) {
+func TestUnicodeStrings(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{"[1, 2, 3][0]", 1},
-		{"[1, 2, 3][1]", 2},
-		{"[1, 2, 3][2]", 3},
-		{"let i = 0; [1][i];", 1},
+		{`len("café")`, 4},
+		{`len("日本語")`, 3},
+		{`let größe = 3; größe * 2`, 6},
+		{`let π = 3; let 名前 = "monkey"; len(名前) + π`, 9},
+		{`"café"[3]`, "é"},
+		{`"café"[-1]`, "é"},
+		{`"café"[0]`, "c"},
+		{`"café"[4]`, nil},
+		{`"café"[-5]`, nil},
+		{`reverse("café")`, "éfac"},
+		{`reverse("")`, ""},
+		{`reverse([1, 2, 3])`, []int64{3, 2, 1}},
+		{`let a = [1, 2]; reverse(a); a`, []int64{1, 2}},
+		{`slice("日本語です", 1, 3)`, "本語"},
+		{`"abc"["a"]`, errorMessage("string index must be INTEGER, got STRING")},
+		{`reverse(1)`, errorMessage("argument to `reverse` not supported, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("Expected a String object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("Expected String value to be %q, instead got %q", expected, str.Value)
+			}
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("Expected an Array object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("Expected %d elements, instead got %d", len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testIntegerObject(t, arr.Elements[i], el)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+// errorMessage marks an expected error in tests whose results can also be strings
+type errorMessage string
+
+func TestSliceStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`slice("hello", 1, 3)`, "el"},
+		{`slice("hello", -3)`, "llo"},
+		{`slice("hello", 0, -1)`, "hell"},
+		{`slice("hello", 4, 2)`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("Expected a String object, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("Expected String value to be %q, instead got %q", tt.expected, str.Value)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`json(1)`, `1`},
+		{`json(-20)`, `-20`},
+		{`json(true)`, `true`},
+		{`json(if (false) { 1 })`, `null`},
+		{`json("a <b> & c")`, `"a <b> & c"`},
+		{`json([])`, `[]`},
+		{`json({})`, `{}`},
+		{`json([1, "two", [true, false]])`, `[1,"two",[true,false]]`},
+		{
+			`json({"name": "monkey", "tags": ["a", "b"], "meta": {"age": 3, 1: "one"}})`,
+			`{"name":"monkey","tags":["a","b"],"meta":{"age":3,"1":"one"}}`,
+		},
+		{`json(fn(x) { x })`, "argument to `json` not supported, got FUNCTION"},
+		{`json([1, len])`, "argument to `json` not supported, got BUILTIN"},
+		{`json({true: 1})`, "unusable as JSON key: BOOLEAN"},
+		{`json({1: "int", "1": "string"})`, "duplicate JSON key: 1"},
+		{`json([{"a": 1, "2": 2, 2: 3}])`, "duplicate JSON key: 2"},
+		{`json(1, 2)`, "wrong number of arguments. got=2, want=1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if err, ok := evaluated.(*object.Error); ok {
+			if err.Message != tt.expected {
+				t.Errorf("Expected error message to be %q, instead got %q", tt.expected, err.Message)
+			}
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("Expected a String object, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("Expected String value to be %q, instead got %q", tt.expected, str.Value)
+		}
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	RegisterBuiltin("double", func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%v, want=1)", len(args))
+		}
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `double` not supported, got %v", args[0].Type())
+		}
+		return &object.Integer{Value: integer.Value * 2}
+	})
+	defer delete(builtins, "double")
+
+	testIntegerObject(t, testEval(`double(21)`), 42)
+	testIntegerObject(t, testEval(`let double = fn(x) { x }; double(21)`), 21)
+
+	RegisterBuiltin("double", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 0}
+	})
+	testIntegerObject(t, testEval(`double(21)`), 0)
+
+	original := builtins["len"]
+	RegisterBuiltin("len", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: -1}
+	})
+	defer func() { builtins["len"] = original }()
+
+	testIntegerObject(t, testEval(`len("abc")`), -1)
+
+	originalEval := builtins["eval"]
+	RegisterBuiltin("eval", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 7}
+	})
+	defer func() { builtins["eval"] = originalEval }()
+
+	testIntegerObject(t, testEval(`eval("1 + 2")`), 7)
+}
+
+func TestEnvBuiltinsAsValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let e = eval; e("1 + 2")`, "3"},
+		{`let a = 1; let v = vars; v()`, `["a", "v"]`},
+		{`let f = fn(x) { let y = 2; let v = vars; v() }; f(1)`, `["f", "v", "x", "y"]`},
+		{`let a = 1; let u = unset; u("a"); vars()`, `["u"]`},
+		{`let x = 5; [eval][0]("x * 2")`, "10"},
+		{`type(vars)`, `"BUILTIN"`},
+		{`vars == vars`, "true"},
+		{`"1 + 2" |> eval`, "3"},
+		{`each({"a": 1}, eval)`, "ERROR: 1:5: builtin function can only be called directly"},
+		{`let vars = fn() { 1 }; vars()`, "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to evaluate to %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+// Monkey strings have no escape sequences, so JSON documents are bound to
+// `doc` from Go instead of being written as string literals
+func testEvalJSON(doc string, input string) object.Object {
+	env := object.NewEnvironment()
+	env.Set("doc", &object.String{Value: doc})
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return Eval(program, env)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5 + 2.5", "4.0"},
+		{"1.5 - 2", "-0.5"},
+		{"2 * 1.25", "2.5"},
+		{"7 / 2.0", "3.5"},
+		{"2.0 ** 3", "8.0"},
+		{"4 ** 0.5", "2.0"},
+		{"-1.5 * 2", "-3.0"},
+		{"1.5 < 2", "true"},
+		{"2 > 2.5", "false"},
+		{"1 < 1.5 < 2", "true"},
+		{"1.5 == 1.5", "true"},
+		{"1 == 1.0", "true"},
+		{"2 != 2.5", "true"},
+		{"let x = 0.5; x = x + 1; x", "1.5"},
+		{"1.5 / 0", "ERROR: 1:5: division by zero"},
+		{"1.5 + true", "ERROR: 1:5: type mismatch: FLOAT + BOOLEAN"},
+		{`1.5 + "a"`, "ERROR: 1:5: type mismatch: FLOAT + STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to evaluate to %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFloatHashKeys(t *testing.T) {
+	evaluated := testEvalJSON(`[1.5, 1.5, 2.5]`, `let f = parseJSON(doc); let h = {f[0]: "a"}; h[f[2]] = "b"; [h[f[1]], h[f[2]], h[1], len(h)]`)
+	if evaluated.Inspect() != `["a", "b", null, 2]` {
+		t.Errorf("Expected float keys to be usable in hashes, instead got %s", evaluated.Inspect())
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	evaluated := testEvalJSON(`{"a":[1,2],"b":true}`, `let h = parseJSON(doc); [h["a"], h["b"], len(h["a"])]`)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("Expected an Array object, instead got %T (%+v)", evaluated, evaluated)
+	}
+
+	arr, ok := result.Elements[0].(*object.Array)
+	if !ok {
+		t.Fatalf("Expected h[\"a\"] to be an Array, instead got %T (%+v)", result.Elements[0], result.Elements[0])
+	}
+	testIntegerObject(t, arr.Elements[0], 1)
+	testIntegerObject(t, arr.Elements[1], 2)
+	testBooleanObject(t, result.Elements[1], true)
+	testIntegerObject(t, result.Elements[2], 2)
+
+	tests := []struct {
+		doc      string
+		input    string
+		expected interface{}
+	}{
+		{`42`, `parseJSON(doc)`, 42},
+		{`true`, `parseJSON(doc)`, true},
+		{`null`, `parseJSON(doc)`, nil},
+		{`[]`, `parseJSON(doc)`, []int64{}},
+		{`[1, -2, 3]`, `parseJSON(doc)`, []int64{1, -2, 3}},
+		{`{"x": {"y": [5]}}`, `parseJSON(doc)["x"]["y"][0]`, 5},
+		{`{"x": 1}`, `parseJSON(doc)["missing"]`, nil},
+		{`"a b"`, `parseJSON(doc)`, "a b"},
+		{`{"b":[1,{"c":null}],"a":"x"}`, `json(parseJSON(doc))`, `{"b":[1,{"c":null}],"a":"x"}`},
+		{`{"a": `, `parseJSON(doc)`, "invalid JSON: unexpected EOF"},
+		{`[1] 2`, `parseJSON(doc)`, "invalid JSON: unexpected data after top-level value"},
+		{``, `parseJSON(1)`, "argument to `parseJSON` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalJSON(tt.doc, tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("Expected an Array object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("Expected %d elements, instead got %d", len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testIntegerObject(t, arr.Elements[i], el)
+			}
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("Expected error message to be %q, instead got %q", expected, obj.Message)
+				}
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("Expected String value to be %q, instead got %q", expected, obj.Value)
+				}
+			default:
+				t.Errorf("Expected a String or Error object, instead got %T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestFloatLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"2.5e-1", 0.25},
+		{"+1.5", 1.5},
+		{"-1.5", -1.5},
+		{"-(-2e-1)", 0.2},
+		{"[0.5][0]", 0.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		f, ok := evaluated.(*object.Float)
+		if !ok {
+			t.Errorf("Expected a Float object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if f.Value != tt.expected {
+			t.Errorf("Expected Float value to be %v, instead got %v", tt.expected, f.Value)
+		}
+	}
+
+	// Floats with integral values still print as floats
+	if inspected := testEval("[1e3, 2.0, 1.5, 1]").Inspect(); inspected != "[1000.0, 2.0, 1.5, 1]" {
+		t.Errorf("Expected %q, instead got %q", "[1000.0, 2.0, 1.5, 1]", inspected)
+	}
+}
+
+func TestRoundingBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"floor(2.7)", 2},
+		{"floor(-2.2)", -3},
+		{"floor(3.0)", 3},
+		{"ceil(2.2)", 3},
+		{"ceil(-2.7)", -2},
+		{"ceil(-0.5)", 0},
+		{"round(2.4)", 2},
+		{"round(2.6)", 3},
+		{"round(-2.6)", -3},
+		// Halves are rounded away from zero, not to even
+		{"round(2.5)", 3},
+		{"round(3.5)", 4},
+		{"round(-2.5)", -3},
+		{"round(0.5)", 1},
+		{"floor(7)", 7},
+		{"round(-7)", -7},
+		{"ceil(1e3)", 1000},
+		{"floor(1e19)", errorMessage("argument to `floor` out of range, got 1e+19")},
+		{"round(-1e300)", errorMessage("argument to `round` out of range, got -1e+300")},
+		{`ceil("1.5")`, errorMessage("argument to `ceil` not supported, got STRING")},
+		{"round()", errorMessage("wrong number of arguments. got=0, want=1)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"sqrt(16)", 4.0},
+		{"sqrt(2.25)", 1.5},
+		{"log(e())", 1.0},
+		{"log(1)", 0.0},
+		{"log10(1000)", 3.0},
+		{"exp(1)", math.E},
+		{"exp(-1.5)", 0.22313016014842982},
+		{"sin(1.5707963267948966)", 1.0},
+		{"cos(pi())", -1.0},
+		{"tan(0.7853981633974483)", 1.0},
+		{"pi()", math.Pi},
+		{"sqrt(-1)", errorMessage("argument to `sqrt` out of domain, got -1")},
+		{"log(0)", errorMessage("argument to `log` out of domain, got 0")},
+		{"log10(-0.5)", errorMessage("argument to `log10` out of domain, got -0.5")},
+		{"exp(1000)", errorMessage("argument to `exp` out of domain, got 1000")},
+		{`sin("a")`, errorMessage("argument to `sin` not supported, got STRING")},
+		{"cos()", errorMessage("wrong number of arguments. got=0, want=1)")},
+		{"pi(1)", errorMessage("wrong number of arguments. got=1, want=0)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case float64:
+			f, ok := evaluated.(*object.Float)
+			if !ok {
+				t.Errorf("Expected a Float object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if math.Abs(f.Value-expected) > 1e-9 {
+				t.Errorf("Expected %s to be %v, instead got %v", tt.input, expected, f.Value)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestParseJSONFloats(t *testing.T) {
+	evaluated := testEvalJSON(`[1.5, 2e3, -0.25]`, `parseJSON(doc)`)
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("Expected an Array object, instead got %T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []float64{1.5, 2000, -0.25}
+	for i, want := range expected {
+		f, ok := arr.Elements[i].(*object.Float)
+		if !ok {
+			t.Errorf("Expected a Float object, instead got %T (%+v)", arr.Elements[i], arr.Elements[i])
+			continue
+		}
+		if f.Value != want {
+			t.Errorf("Expected Float value to be %v, instead got %v", want, f.Value)
+		}
+	}
+
+	if f, ok := testEvalJSON(`1.5`, `+parseJSON(doc)`).(*object.Float); !ok || f.Value != 1.5 {
+		t.Errorf("Expected unary plus to leave floats unchanged, instead got %+v", f)
+	}
+
+	str, ok := testEvalJSON(`[1.5, -0.25]`, `json(parseJSON(doc))`).(*object.String)
+	if !ok || str.Value != "[1.5,-0.25]" {
+		t.Errorf("Expected floats to round-trip, instead got %+v", str)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 + 3, 4 * 5];"
+	evaluated := testEval(input)
+
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("Expected object to be Array, instead got %T", evaluated)
+	}
+
+	if len(arr.Elements) != 3 {
+		t.Fatalf("Expected array to have 3 elements, instead got %v", len(arr.Elements))
+	}
+
+	testIntegerObject(t, arr.Elements[0], 1)
+	testIntegerObject(t, arr.Elements[1], 5)
+	testIntegerObject(t, arr.Elements[2], 20)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
 		{"[1, 2, 3][1 + 1];", 3},
 		{"let myArray = [1, 2, 3]; myArray[2];", 3},
 		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
 		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
 		{"[1, 2, 3][3]", nil},
-		{"[1, 2, 3][-1]", nil},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", nil},
+		{"let myArray = [1, 2, 3]; myArray[-1] + myArray[-2];", 5},
+		{"[][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-3:-1]", "[2, 3]"},
+		{"[1, 2, 3, 4][1:10]", "[2, 3, 4]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"let a = [1, 2]; let b = a[:]; push(b, 3); a", "[1, 2]"},
+		{"let i = 1; [1, 2, 3][i:i + 1]", "[2]"},
+		{`"héllo"[1:3]`, `"él"`},
+		{`"hello"[-3:]`, `"llo"`},
+		{`[1, 2]["a":]`, "ERROR: 1:7: slice index must be INTEGER, got STRING"},
+		{"[1, 2][:true]", "ERROR: 1:7: slice index must be INTEGER, got BOOLEAN"},
+		{"5[1:2]", "ERROR: 1:2: slice operator not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to evaluate to %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+
+	if !ok {
+		t.Fatalf("Expected a Hash, instead got %T(%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():     1,
+		(&object.String{Value: "two"}).HashKey():     2,
+		(&object.String{Value: "three"}).HashKey():   3,
+		(&object.Integer{Value: int64(4)}).HashKey(): 4,
+		TRUE.HashKey():  5,
+		FALSE.HashKey(): 6,
+	}
+
+	if len(expected) != len(result.Pairs) {
+		t.Fatalf("The length are not equal, expected %v, received %v", len(expected), len(result.Pairs))
+	}
+
+	for key, value := range expected {
+		evaluatedPair, ok := result.Pairs[key]
+		if !ok {
+			t.Errorf("Value for key is absent")
+		}
+		testIntegerObject(t, evaluatedPair.Value, value)
+	}
+}
+
+func TestHashLiteralWithoutKeys(t *testing.T) {
+	literal := &ast.HashLiteral{Pairs: map[ast.Expression]ast.Expression{
+		&ast.StringLiteral{Value: "one"}: &ast.IntegerLiteral{Value: 1},
+		&ast.StringLiteral{Value: "two"}: &ast.IntegerLiteral{Value: 2},
+	}}
+
+	// Macro expansion rebuilds the literal, which mustn't lose its pairs
+	program := &ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: literal}}}
+	expanded, err := ExpandMacros(program, object.NewEnvironment())
+	if err != nil {
+		t.Fatalf("ExpandMacros returned an error: %v", err)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey(): 1,
+		(&object.String{Value: "two"}).HashKey(): 2,
+	}
+
+	for _, node := range []ast.Node{literal, expanded} {
+		evaluated := Eval(node, object.NewEnvironment())
+		result, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Fatalf("Expected a Hash, instead got %T(%+v)", evaluated, evaluated)
+		}
+
+		if len(expected) != len(result.Pairs) {
+			t.Fatalf("The length are not equal, expected %v, received %v", len(expected), len(result.Pairs))
+		}
+		for key, value := range expected {
+			pair, ok := result.Pairs[key]
+			if !ok {
+				t.Errorf("Value for key is absent")
+				continue
+			}
+			testIntegerObject(t, pair.Value, value)
+		}
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2}`, "{\n\"b\" : 1,\n\"a\" : 2\n}"},
+		{`keys({"b": 1, "a": 2, 3: 3})`, `["b", "a", 3]`},
+		{`values({"b": 1, "a": 2, 3: 3})`, `[1, 2, 3]`},
+		{`let h = {"b": 1, "a": 2}; h["b"] = 3; h["c"] = 4; keys(h)`, `["b", "a", "c"]`},
+		{`let h = {"b": 1, "a": 2}; h["b"] = 3; values(h)`, `[3, 2]`},
+		{`keys(delete({"c": 1, "b": 2, "a": 3}, "b"))`, `["c", "a"]`},
+		{`keys({})`, `[]`},
+		{`entries({"b": 1, "a": [2], 3: true})`, `[["b", 1], ["a", [2]], [3, true]]`},
+		{`let h = {"b": 1, "a": 2}; h["b"] = 3; h["c"] = 4; entries(h)`, `[["b", 3], ["a", 2], ["c", 4]]`},
+		{`entries({})`, `[]`},
+		{`entries(merge({"a": 1}, {"b": 2}))`, `[["a", 1], ["b", 2]]`},
+		{`entries(merge({"a": 1, "b": 2}, {"b": 3, "c": 4}, {"a": 5}))`, `[["a", 5], ["b", 3], ["c", 4]]`},
+		{`entries(merge({1: "int"}, {"1": "string"}, {true: "bool"}))`, `[[1, "int"], ["1", "string"], [true, "bool"]]`},
+		{`entries(merge({}, {"a": 1}))`, `[["a", 1]]`},
+		{`let a = {"x": 1}; merge(a, {"x": 2, "y": 3}); entries(a)`, `[["x", 1]]`},
+		{`let e = entries({"x": 1})[0]; e[0] + ": " + json(e[1])`, `"x: 1"`},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 5; i++ {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("Expected %q, instead got %q", tt.expected, evaluated.Inspect())
+				break
+			}
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys([1])`, "argument to `keys` not supported, got ARRAY"},
+		{`values(1)`, "argument to `values` not supported, got INTEGER"},
+		{`entries([[1, 2]])`, "argument to `entries` not supported, got ARRAY"},
+		{`merge({"a": 1}, [1])`, "argument to `merge` not supported, got ARRAY"},
+		{`merge()`, "wrong number of arguments. got=0, want=1+)"},
+		{`keys({}, {})`, "wrong number of arguments. got=2, want=1)"},
+	}
+
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`{"foo": 5}["foo"]`,
+			5,
+		},
+		{
+			`{"foo": 5}["bar"]`,
+			nil,
+		},
+		{
+			`let key = "foo"; {"foo": 5}[key]`,
+			5,
+		},
+		{
+			`{}["foo"]`,
+			nil,
+		},
+		{
+			`{5: 5}[5]`,
+			5,
+		},
+		{
+			`{true: 5}[true]`,
+			5,
+		},
+		{
+			`{false: 5}[false]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[1, 2]]`,
+			5,
+		},
+		{
+			`let key = [1, [2, 3]]; {key: 5}[[1, [2, 3]]]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[2, 1]]`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	if obj != NULL {
+		t.Errorf("Expected object to be NULL, instead got %T (%+v)", obj, obj)
+		return false
+	}
+	return true
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+
+	if !ok {
+		t.Errorf("Expected object to be Integer, instead got %T (%v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("Expected integer value to be %v, instead got %v", expected, result.Value)
+		return false
+	}
+
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+
+	if !ok {
+		t.Errorf("Expected object to be Boolean, instead got %T", obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("Expected object value to be %v, instead got %v", expected, result.Value)
+		return false
+	}
+
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+
+	if !ok {
+		t.Errorf("Expected object to be String, instead got %T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("Expected string value to be %q, instead got %q", expected, result.Value)
+		return false
+	}
+
+	return true
+}
+
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	env := object.NewEnvironment()
+
+	program := p.ParseProgram()
+
+	return Eval(program, env)
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2, 3]; a[1] = 9; a[1];", 9},
+		{"let a = [1, 2, 3]; a[0] = a[1] + a[2]; a[0];", 5},
+		{"let a = [1, 2, 3]; a[2] = 7;", 7},
+		{"let a = [[1], [2]]; a[1][0] = 5; a[1][0];", 5},
+		{"let a = [1, 2]; let b = [0, 0]; a[0] = b[1] = 4; a[0] + b[1];", 8},
+		{`let h = {"k": 1}; h["k"] = 2; h["k"];`, 2},
+		{`let h = {}; h["k"] = 3; h["k"];`, 3},
+		{`let h = {}; h[1] = 3; h[true] = 4; h[1] + h[true];`, 7},
+		{"let a = [1, 2, 3]; a[3] = 1;", "index out of range: 3 (length 3)"},
+		{"let a = [1, 2, 3]; a[-1] = 9; a[2];", 9},
+		{"let a = [1, 2, 3]; a[-3] = 9; a[0];", 9},
+		{"let a = [1, 2, 3]; a[-1]++; a[-1];", 4},
+		{"let a = [1, 2, 3]; a[-2]--; a[1];", 1},
+		{"let a = [1, 2, 3]; a[-4] = 1;", "index out of range: -4 (length 3)"},
+		{"let a = [1, 2, 3]; a[-4]++;", "unknown operator: NULL++"},
+		{`let a = [1]; a["x"] = 1;`, "array index must be INTEGER, got STRING"},
+		{`let h = {}; h[fn(x) { x }] = 1;`, "unusable as hash key: FUNCTION"},
+		{`let s = "abc"; s[0] = 1;`, "index assignment not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDeleteBuiltin(t *testing.T) {
+	input := `let h = {"a": 1, "b": 2};
+	let removed = delete(h, "a");
+	let missing = delete(h, "c");
+	[removed, missing, h];`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("Expected an Array, instead got %T (%+v)", evaluated, evaluated)
+	}
+
+	removed := result.Elements[0].(*object.Hash)
+	missing := result.Elements[1].(*object.Hash)
+	original := result.Elements[2].(*object.Hash)
+
+	a := (&object.String{Value: "a"}).HashKey()
+	b := (&object.String{Value: "b"}).HashKey()
+
+	if len(removed.Pairs) != 1 {
+		t.Fatalf("Expected 1 pair after delete, instead got %v", len(removed.Pairs))
+	}
+	if _, ok := removed.Pairs[a]; ok {
+		t.Errorf("Expected key \"a\" to be deleted")
+	}
+	testIntegerObject(t, removed.Pairs[b].Value, 2)
+
+	if missing != original {
+		t.Errorf("Expected deleting a missing key to return the original hash")
+	}
+	if len(original.Pairs) != 2 {
+		t.Errorf("Expected the original hash to be untouched, instead got %v pairs", len(original.Pairs))
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`delete({}, fn(x) { x })`, "unusable as hash key: FUNCTION"},
+		{`delete([1], 0)`, "argument to `delete` not supported, got ARRAY"},
+		{`delete({})`, "wrong number of arguments. got=1, want=2)"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("Expected an Error object for %v", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("Expected Message to be %v, instead got %v", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLine    int
+		expectedColumn  int
+		expectedInspect string
+	}{
+		{
+			"let a = 1;\nlet b = 2;\nlet c = a + foobar;",
+			3, 13,
+			"ERROR: 3:13: identifier not found: foobar",
+		},
+		{
+			"let a = 1;\n  a + true",
+			2, 5,
+			"ERROR: 2:5: type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"let f = fn(x) {\n  -x\n};\nf(true)",
+			2, 3,
+			"ERROR: 2:3: unknown operator: -BOOLEAN\n    at f (4:2)",
+		},
+		{
+			`len(1)`,
+			1, 4,
+			"ERROR: 1:4: argument to `len` not supported, got INTEGER",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected object to be Error, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Line != tt.expectedLine || errObj.Column != tt.expectedColumn {
+			t.Errorf("Expected error at %v:%v, instead got %v:%v",
+				tt.expectedLine, tt.expectedColumn, errObj.Line, errObj.Column)
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("Expected Inspect to be %q, instead got %q", tt.expectedInspect, errObj.Inspect())
+		}
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedStack []string
+	}{
+		{
+			"let inner = fn(x) { x + true };\nlet outer = fn(x) { inner(x) };\nouter(1)",
+			[]string{"inner (2:26)", "outer (3:6)"},
+		},
+		{
+			"fn(x) { -x }(true)",
+			[]string{"<anonymous> (1:13)"},
+		},
+		{
+			"let a = 1 + true; a",
+			nil,
+		},
+		{
+			`let fail = fn() { 1 + true }; let fns = {"f": fail}; fns["f"]()`,
+			[]string{"fail (1:62)"},
+		},
+		{
+			"let make = fn() { fn() { -true } }; make()()",
+			[]string{"<anonymous> (1:43)"},
+		},
+		{
+			"let f = fn(n) { if (n == 0) { n + true } else { 1 + f(n - 1) } };\nf(25)",
+			repeatFrame("f (1:54)", 20),
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected object to be Error, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if fmt.Sprint(errObj.Stack) != fmt.Sprint(tt.expectedStack) {
+			t.Errorf("Expected stack to be %q, instead got %q", tt.expectedStack, errObj.Stack)
+		}
+	}
+
+	evaluated := testEval("let f = fn(n) { if (n == 0) { -true } else { f(n - 1) + 1 } };\nf(21)")
+	expected := "ERROR: 1:31: unknown operator: -BOOLEAN" +
+		strings.Repeat("\n    at f (1:47)", 20) + "\n    ... 2 more"
+	if evaluated.Inspect() != expected {
+		t.Errorf("Expected %q, instead got %q", expected, evaluated.Inspect())
+	}
+}
+
+func repeatFrame(frame string, n int) []string {
+	frames := make([]string, n)
+	for i := range frames {
+		frames[i] = frame
+	}
+	return frames
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (let i = 0; i < 5; i = i + 1) { sum = sum + i; }; sum;", 10},
+		{"let sum = 0; for (let i = 0; i < 5; i = i + 1) { sum = sum + i } sum", 10},
+		{"let sum = 0; let i = 0; for (; i < 3;) { sum = sum + 2; i = i + 1; } sum", 6},
+		{"let i = 10; for (i = 0; i < 3; i = i + 1) { } i", 3},
+		{"for (let i = 0; i < 5; i = i + 1) { } i", "identifier not found: i"},
+		{"for (let i = 0; i < 5; i = i + 1) { x }", "identifier not found: x"},
+		{"for (let i = 0; i < 5; i = i + 1) { i + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for (let i = 0; i + true; i = i + 1) { }", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn() { for (let i = 0; i < 5; i = i + 1) { if (i == 3) { return i; } } 99 }; f()", 3},
+		{"for (let i = 0; i < 0; i = i + 1) { 1 }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = 1; a = 2; a;", 2},
+		{"let a = 1; a = a + 1;", 2},
+		{"let a = 1; let b = 1; a = b = 5; a + b;", 10},
+		{"let a = 1; let f = fn() { a = 5 }; f(); a;", 5},
+		{"let a = 1; let f = fn(a) { a = 5 }; f(2); a;", 1},
+		{"b = 1;", "identifier not found: b"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLoopControlFlow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i = i + 1; } i;", 5},
+		{"let i = 0; while (true) { i = i + 1; if (i == 3) { break; } } i;", 3},
+		{"let i = 0; let sum = 0; while (i < 5) { i = i + 1; if (i == 2) { continue; } sum = sum + i; } sum;", 13},
+		{"let sum = 0; for (let i = 0; i < 10; i = i + 1) { if (i == 4) { break } sum = sum + i; } sum;", 6},
+		{"let sum = 0; for (let i = 0; i < 5; i = i + 1) { if (i == 1) { continue } sum = sum + i; } sum;", 9},
+		{"let n = 0; let i = 0; while (i < 3) { i = i + 1; let j = 0; while (true) { j = j + 1; if (j == 2) { break; } } n = n + j; } n;", 6},
+		{"let f = fn() { let i = 0; while (true) { i = i + 1; if (i == 7) { return i; } } }; f();", 7},
+		{"while (false) { 1 }", nil},
+		{"break;", "break outside of a loop"},
+		{"if (true) { continue; }", "continue outside of a loop"},
+		{"let f = fn() { break; }; while (true) { f(); }", "break outside of a loop"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTernaryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"1 < 2 ? 10 : 20", 10},
+		{"null_value ? 1 : 2", "identifier not found: null_value"},
+		{"let x = 5; x > 3 ? x * 2 : x", 10},
+		{"let x = 2; x == 1 ? 10 : x == 2 ? 20 : 30", 20},
+		{"let x = 3; x == 1 ? 10 : x == 2 ? 20 : 30", 30},
+		{"true ? (false ? 1 : 2) : 3", 2},
+		{"false ? foobar : 3", 3},
+		{"true ? 3 : foobar", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNamedFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5)", 120},
+		{"fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } } fact(10)", 3628800},
+		{"let f = fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; f(4)", 24},
+		{"let f = fn fact(n) { n }; fact(4)", "identifier not found: fact"},
+		{"fn(x) { x * 2 }(3)", 6},
+		{"let outer = fn() { fn inner(n) { n + 1 } inner(1) }; outer()", 2},
+		{"let outer = fn() { fn inner(n) { n + 1 } inner(1) }; outer(); inner(1)", "identifier not found: inner"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDefaultParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3)", 9},
+		{"let n = 5; let f = fn(x = n) { x }; n = 6; f()", 6},
+		{"let f = fn(x = 1, y = 2) { x * 10 + y }; f()", 12},
+		{"let f = fn(x, y = 10) { x + y }; f()", "wrong number of arguments. got=0, want=1..2)"},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2, 3)", "wrong number of arguments. got=3, want=1..2)"},
+		{"let f = fn(x, y) { x + y }; f(1)", "wrong number of arguments. got=1, want=2)"},
+		{"let f = fn(x = foobar) { x }; f()", "identifier not found: foobar"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(first, ...rest) { rest }; f(1, 2, 3)", []int64{2, 3}},
+		{"let f = fn(first, ...rest) { rest }; f(1)", []int64{}},
+		{"let f = fn(...all) { all }; f(1, 2)", []int64{1, 2}},
+		{"let f = fn(first, ...rest) { first }; f(1, 2, 3)", 1},
+		{"let f = fn(a, b = 5, ...rest) { [a, b, len(rest)] }; f(1)", []int64{1, 5, 0}},
+		{"let f = fn(a, b = 5, ...rest) { [a, b, len(rest)] }; f(1, 2, 3, 4)", []int64{1, 2, 2}},
+		{"let f = fn(first, second, ...rest) { rest }; f(1)", "wrong number of arguments. got=1, want=2+)"},
+		{"let f = fn(first, ...rest) { rest }; f()", "wrong number of arguments. got=0, want=1+)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("Expected an Array, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("Expected %v elements, instead got %v", len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range arr.Elements {
+				testIntegerObject(t, el, expected[i])
+			}
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected Message to be %v, instead got %v", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	input := "let f = fn(n) { 1 + f(n + 1) }; f(0)"
+
+	env := object.NewEnvironment()
+	evaluated := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "maximum recursion depth exceeded" {
+		t.Errorf("Expected Message to be %v, instead got %v", "maximum recursion depth exceeded", errObj.Message)
+	}
+
+	if calls := env.EnterCall(); calls != 1 {
+		t.Errorf("Expected call depth to be reset after the error, instead got %v", calls-1)
+	}
+	env.LeaveCall()
+
+	defer func(depth int) { MaxCallDepth = depth }(MaxCallDepth)
+	MaxCallDepth = 10
+
+	input = "let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } };"
+	testIntegerObject(t, testEval(input+"f(9)"), 9)
+	if _, ok := testEval(input + "f(10)").(*object.Error); !ok {
+		t.Errorf("Expected recursion past MaxCallDepth to produce an error")
+	}
+}
+
+func TestConcurrentEvaluations(t *testing.T) {
+	input := `let f = fn(n) { if (n == 0) { -true } else { f(n - 1) + 1 } }; f(%d)`
+
+	var wg sync.WaitGroup
+	errs := make([]object.Object, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = testEval(fmt.Sprintf(input, i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, evaluated := range errs {
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if len(errObj.Stack) != i+1 {
+			t.Errorf("Expected f(%d) to have %d frames, instead got %v", i, i+1, errObj.Stack)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	defer func(depth int) { MaxCallDepth = depth }(MaxCallDepth)
+	MaxCallDepth = 100
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn loop(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(100000)", 0},
+		{"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(10000, 0)", 50005000},
+		{"let count = fn(n) { n == 0 ? \"done\" : count(n - 1) }; count(5000)", "done"},
+		{"let f = fn(n) { if (n > 0) { if (n > 500) { f(n - 1) } else { f(n - 3) } } else { n } }; f(1001)", -1},
+		{"let f = fn(n, acc = 0) { if (n == 0) { acc } else { f(n - 1, acc + 1) } }; f(500)", 500},
+		{"let fs = []; let f = fn(n) { fs = push(fs, fn() { n }); if (n > 0) { f(n - 1) } }; f(2); fs[0]() + fs[2]()", 2},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(1000)", "maximum recursion depth exceeded"},
+		{"let f = fn(n) { if (n == 0) { foobar } else { f(n - 1) } }; f(1000)", "identifier not found: foobar"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { f() } }; f(1)", "wrong number of arguments. got=0, want=1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if str, ok := evaluated.(*object.String); ok {
+				if str.Value != expected {
+					t.Errorf("Expected %q, instead got %q", expected, str.Value)
+				}
+				continue
+			}
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestEvalBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`eval("1 + 2")`, 3},
+		{`let a = 2; eval("a * 3")`, 6},
+		{`eval("let x = 5;"); x`, 5},
+		{`let f = eval("fn(x) { x * 2 }"); f(4)`, 8},
+		{`eval("")`, nil},
+		{`let eval = fn(x) { x }; eval(7)`, 7},
+		{`eval("1 +")`, "parser errors: 1:4: No prefix parse function found for EOF"},
+		{`eval("foobar")`, "identifier not found: foobar"},
+		{`eval(1)`, "argument to `eval` not supported, got INTEGER"},
+		{`eval()`, "wrong number of arguments. got=0, want=1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+
+	defer func(depth int) { MaxCallDepth = depth }(MaxCallDepth)
+	MaxCallDepth = 50
+
+	env := object.NewEnvironment()
+	evaluated := Eval(parser.New(lexer.New(`let f = fn() { eval("f()") }; f()`)).ParseProgram(), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "maximum recursion depth exceeded" {
+		t.Errorf("Expected Message to be %v, instead got %v", "maximum recursion depth exceeded", errObj.Message)
+	}
+	if calls := env.EnterCall(); calls != 1 {
+		t.Errorf("Expected call depth to be reset after the error, instead got %v", calls-1)
+	}
+	env.LeaveCall()
+}
+
+func TestParseBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`parse("1 + 2 * 3")`, "(1 + (2 * 3))"},
+		{`parse("-a * b")`, "((-a) * b)"},
+		{`parse("a + b * c ** 2 ** 3")`, "(a + (b * (c ** (2 ** 3))))"},
+		{`parse("let x = 1; x")`, "let x = 1;x"},
+		{`parse("foobar")`, "foobar"},
+		{`parse("")`, ""},
+		{`parse("1 +")`, errorMessage("parser errors: 1:4: No prefix parse function found for EOF")},
+		{`parse(1)`, errorMessage("argument to `parse` not supported, got INTEGER")},
+		{`parse()`, errorMessage("wrong number of arguments. got=0, want=1)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLoadBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	lib := writeFile("lib.mk", "let square = fn(x) { x * x };\nlet answer = 42;\nanswer")
+	a := writeFile("a.mk", fmt.Sprintf("load(\"%s\")", filepath.Join(dir, "b.mk")))
+	writeFile("b.mk", fmt.Sprintf("load(\"%s\")", a))
+	broken := writeFile("broken.mk", "let = 1;")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{fmt.Sprintf(`load("%s")`, lib), 42},
+		{fmt.Sprintf(`load("%s"); square(answer) - square(40)`, lib), 164},
+		{fmt.Sprintf(`load("%s"); load("%s"); square(3)`, lib, lib), 9},
+		{fmt.Sprintf(`load("%s")`, a), fmt.Sprintf("cyclic load of %s", a)},
+		{fmt.Sprintf(`load("%s")`, broken), "parser errors: 1:5: Expected token IDENT, instead got ="},
+		{`load("/does/not/exist.mk")`, "file not found: /does/not/exist.mk"},
+		{`load(1)`, "argument to `load` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+
+	if len(loading) != 0 {
+		t.Errorf("Expected no files to be loading after evaluation, instead got %v", loading)
+	}
+}
+
+func TestClock(t *testing.T) {
+	defer func(now func() time.Time) { Now = now }(Now)
+	Now = func() time.Time { return time.Unix(1700000000, 123456789) }
+
+	testIntegerObject(t, testEval(`clock()`), 1700000000123)
+	testIntegerObject(t, testEval(`let start = clock(); clock() - start`), 0)
+
+	evaluated := testEval(`clock(1)`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "wrong number of arguments. got=1, want=0)" {
+		t.Errorf("Expected error message to be %q, instead got %q", "wrong number of arguments. got=1, want=0)", errObj.Message)
+	}
+}
+
+func TestReadline(t *testing.T) {
+	defer func(in *bufio.Reader, out io.Writer) { stdin, Stdout = in, out }(stdin, Stdout)
+	SetStdin(strings.NewReader("first\nsecond\r\n\nlast"))
+
+	testStringObject(t, testEval(`readline()`), "first")
+	testStringObject(t, testEval(`readline()`), "second")
+	testStringObject(t, testEval(`readline()`), "")
+	testStringObject(t, testEval(`readline()`), "last")
+	testNullObject(t, testEval(`readline()`))
+	testNullObject(t, testEval(`readline()`))
+
+	out := &bytes.Buffer{}
+	SetStdin(strings.NewReader("Monkey\nGopher\n"))
+	Stdout = out
+	testEval(`let name = readline(); while (!isNull(name)) { puts("hi " + name); name = readline() }`)
+	if out.String() != "\"hi Monkey\"\n\"hi Gopher\"\n" {
+		t.Errorf("Expected output %q, instead got %q", "\"hi Monkey\"\n\"hi Gopher\"\n", out.String())
+	}
+
+	// Readers that can't be compared with == work too
+	SetStdin(uncomparableReader{Reader: strings.NewReader("a\nb")})
+	testStringObject(t, testEval(`readline()`), "a")
+	testStringObject(t, testEval(`readline()`), "b")
+
+	evaluated := testEval(`readline(1)`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "wrong number of arguments. got=1, want=0)" {
+		t.Errorf("Expected error message to be %q, instead got %q", "wrong number of arguments. got=1, want=0)", errObj.Message)
+	}
+}
+
+type uncomparableReader struct {
+	io.Reader
+	_ []byte
+}
+
+func TestConditionsEvaluatedOnce(t *testing.T) {
+	defer func(out io.Writer) { Stdout = out }(Stdout)
+
+	check := `let check = fn(value) { puts("check"); value };`
+	tests := []struct {
+		input    string
+		expected int64
+		checks   int
+	}{
+		{"if (check(true)) { 1 } else { 2 }", 1, 1},
+		{"if (check(false)) { 1 } else { 2 }", 2, 1},
+		{"if (check(null)) { 1 }; 3", 3, 1},
+		{"check(true) ? 1 : 2", 1, 1},
+		{"check(0) ? check(1) : 2", 1, 2},
+		{"let f = fn() { if (check(false)) { 1 } else { 2 } }; f()", 2, 1},
+		{"let f = fn() { return check(true) ? 1 : 2 }; f()", 1, 1},
+		{"let f = fn(n) { if (check(n == 0)) { n } else { f(n - 1) } }; f(2)", 0, 3},
+		{"if (check(1) < check(2) < check(3)) { 1 } else { 2 }", 1, 3},
+		{"if (check(2) < check(1) < check(3)) { 1 } else { 2 }", 2, 2},
+		{"let n = 0; while (check(n < 2)) { n++ }; n", 2, 3},
+		{"let n = 0; for (let i = 0; check(i < 3); i++) { n = n + i }; n", 3, 4},
+		{"match check(2) { 1 => 10, 2 => 20, _ => 30 }", 20, 1},
+		{"[if (check(true)) { 1 }][0]", 1, 1},
+	}
+
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		Stdout = out
+
+		testIntegerObject(t, testEval(check+tt.input), tt.expected)
+		if checks := strings.Count(out.String(), "\"check\"\n"); checks != tt.checks {
+			t.Errorf("Expected %q to run %v checks, instead it ran %v", tt.input, tt.checks, checks)
+		}
+	}
+
+	// Tracing doesn't evaluate anything twice either
+	defer func(w io.Writer) { Trace = w }(Trace)
+	Trace = io.Discard
+	out := &bytes.Buffer{}
+	Stdout = out
+	testIntegerObject(t, testEval(check+"if (check(true)) { 1 } else { 2 }"), 1)
+	if checks := strings.Count(out.String(), "\"check\"\n"); checks != 1 {
+		t.Errorf("Expected a traced condition to run once, instead it ran %v times", checks)
+	}
+}
+
+func TestRandomNumbers(t *testing.T) {
+	input := `seed(42); [rand(100), rand(100), rand(100), rand(1000000)]`
+
+	expected := rand.New(rand.NewSource(42))
+	want := []int64{expected.Int63n(100), expected.Int63n(100), expected.Int63n(100), expected.Int63n(1000000)}
+
+	for run := 0; run < 2; run++ {
+		evaluated := testEval(input)
+		arr, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Fatalf("Expected an Array object, instead got %T (%+v)", evaluated, evaluated)
+		}
+		for i, el := range want {
+			testIntegerObject(t, arr.Elements[i], el)
+		}
+	}
+
+	testIntegerObject(t, testEval(`rand(1)`), 0)
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`rand(0)`, "argument to `rand` must be positive, got 0"},
+		{`rand(-5)`, "argument to `rand` must be positive, got -5"},
+		{`rand("a")`, "argument to `rand` not supported, got STRING"},
+		{`seed(true)`, "argument to `seed` not supported, got BOOLEAN"},
+		{`seed()`, "wrong number of arguments. got=0, want=1)"},
+	}
+
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestVarsBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`vars()`, `[]`},
+		{`let b = 1; let a = 2; let c = "x"; vars()`, `["a", "b", "c"]`},
+		{`let a = 1; let f = fn(b) { let c = 3; let a = 4; vars() }; f(2)`, `["a", "b", "c", "f"]`},
+		{`let len = 1; vars()`, `["len"]`},
+		{`for (let i = 0; i < 1; i = i + 1) { let inner = 1 }; vars()`, `[]`},
+		{`vars(1)`, "wrong number of arguments. got=1, want=0)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %s, instead got %s", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestUnsetBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x = 1; unset("x")`, true},
+		{`unset("missing")`, false},
+		{`let x = 1; unset("x"); unset("x")`, false},
+		{`let x = 1; unset("x"); x`, "identifier not found: x"},
+		{`let x = 1; let f = fn() { let x = 2; unset("x"); x }; f()`, 1},
+		{`let len = 1; unset("len"); len("ab")`, 2},
+		{`unset(1)`, "argument to `unset` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSortBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sort([3, 1, 2])`, `[1, 2, 3]`},
+		{`sort([])`, `[]`},
+		{`sort(["pear", "apple", "fig"])`, `["apple", "fig", "pear"]`},
+		{`sort([true, false, true])`, `[false, true, true]`},
+		{`let n = if (false) { 1 }; sort([2, n, 1])`, `[1, 2, null]`},
+		{`let a = [2, 1]; sort(a); a`, `[2, 1]`},
+		{`sort([1, "a"])`, "can't sort: cannot compare STRING and INTEGER"},
+		{`sort([[1], [2]])`, "can't sort: cannot compare ARRAY and ARRAY"},
+		{`sort(1)`, "argument to `sort` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %s, instead got %s", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCopyBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`copy(5)`, `5`},
+		{`copy("abc")`, `"abc"`},
+		{`copy([1, 2, 3])`, `[1, 2, 3]`},
+		{`let a = [1, 2]; let b = copy(a); b[0] = 5; a`, `[1, 2]`},
+		{`let a = [1, 2]; let b = copy(a); b[0] = 5; b`, `[5, 2]`},
+		{`let a = [[1], [2]]; let b = copy(a); b[0][0] = 5; a`, `[[1], [2]]`},
+		{`let h = {"a": 1}; let c = copy(h); c["a"] = 2; h["a"]`, `1`},
+		{`let h = {"a": [1]}; let c = copy(h); c["a"][0] = 2; h["a"]`, `[1]`},
+		{`let h = {"b": 1, "a": 2}; keys(copy(h))`, `["b", "a"]`},
+		{`let f = fn(x) { x }; copy(f) == f`, `true`},
+		{`let a = [1]; a[0] = a; copy(a)`, `[[...]]`},
+		{`let a = [1]; a[0] = a; let b = copy(a); b[0][0][0] == b`, `true`},
+		{`let a = [1]; a[0] = a; let b = copy(a); a[0] = 5; [a, b]`, `[[5], [[...]]]`},
+		{`let h = {}; h["self"] = h; let c = copy(h); c["self"]["self"] == c`, `true`},
+		{`let x = [1]; let a = [x, x]; let b = copy(a); b[0][0] = 2; b`, `[[2], [2]]`},
+		{`copy(1, 2)`, "wrong number of arguments. got=2, want=1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %s, instead got %s", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [1]; a[0] = a; a`, `[[...]]`},
+		{`let a = [1, 2]; a[1] = [a]; a`, `[1, [[...]]]`},
+		{`let x = [1]; [x, x]`, `[[1], [1]]`},
+		{`let h = {"a": 1}; h["self"] = h; h`, "{\n\"a\" : 1,\n\"self\" : {...}\n}"},
+		{`let a = [1]; let h = {"a": a}; a[0] = h; a`, "[{\n\"a\" : [...]\n}]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRepeatBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`repeat(0, 3)`, `[0, 0, 0]`},
+		{`repeat("a", 2)`, `["a", "a"]`},
+		{`repeat(1, 0)`, `[]`},
+		{`repeat([1, 2], 2)`, `[[1, 2], [1, 2]]`},
+		{`let a = repeat([0], 2); a[0][0] = 1; a`, `[[1], [0]]`},
+		{`let row = [0]; let a = repeat(row, 2); a[1][0] = 1; row`, `[0]`},
+		{`let h = repeat({"a": 0}, 2); h[0]["a"] = 1; h[1]["a"]`, `0`},
+		{`repeat(0, -1)`, "argument to `repeat` must not be negative, got -1"},
+		{`repeat(0, "3")`, "argument to `repeat` not supported, got STRING"},
+		{`repeat(0)`, "wrong number of arguments. got=1, want=2)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %s, instead got %s", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestZipBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`zip([1, 2], ["a", "b"])`, `[[1, "a"], [2, "b"]]`},
+		{`zip([1, 2, 3], ["a"])`, `[[1, "a"]]`},
+		{`zip([1], [true, false])`, `[[1, true]]`},
+		{`zip([], [1, 2])`, `[]`},
+		{`zip(1, [1])`, "argument to `zip` not supported, got INTEGER"},
+		{`zip([1], "a")`, "argument to `zip` not supported, got STRING"},
+		{`zip([1])`, "wrong number of arguments. got=1, want=2)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %s, instead got %s", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCurryBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b) { a + b }; let inc = curry(add)(1); inc(5)", 6},
+		{"let addMul = fn(a, b, c) { a + b * c }; curry(addMul)(1)(2)(3)", 7},
+		{"let addMul = fn(a, b, c) { a + b * c }; curry(addMul)(1, 2)(3)", 7},
+		{"let addMul = fn(a, b, c) { a + b * c }; curry(addMul)(1)(2, 3)", 7},
+		{"let addMul = fn(a, b, c) { a + b * c }; let f = curry(addMul)(1); f(2)(3) + f(3)(4)", 20},
+		{"let f = fn(a, b = 10) { a + b }; curry(f)(1)", 11},
+		{"let f = fn(a, b = 10) { a + b }; curry(f, 2)(1)(2)", 3},
+		{"let sum = fn(...xs) { len(xs) }; curry(sum, 3)(1)(2)(3)", 3},
+		{"let p = curry(push, 2)([1]); len(p(2))", 2},
+		{"curry(fn() { 5 })()", 5},
+		{"let add = fn(a, b) { a + b }; add(1)", errorMessage("wrong number of arguments. got=1, want=2)")},
+		{"let add = fn(a, b) { a + b }; curry(add)(1)(2, 3)", errorMessage("wrong number of arguments. got=3, want=2)")},
+		{"curry(len)", errorMessage("`curry` needs the arity of a builtin")},
+		{"curry(1)", errorMessage("argument to `curry` not supported, got INTEGER")},
+		{"curry(len, -1)", errorMessage("argument to `curry` must not be negative, got -1")},
+		{"curry()", errorMessage("wrong number of arguments. got=0, want=1..2)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestArityBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b) { a + b }; arity(add)", 2},
+		{"arity(fn(x) { x })", 1},
+		{"arity(fn() { 1 })", 0},
+		{"arity(fn(a, b = 1, c = 2) { a })", 3},
+		{"arity(fn(a, ...rest) { a })", -1},
+		{"arity(len)", -1},
+		{"arity(curry(fn(a, b) { a + b }))", -1},
+		{"arity(1)", errorMessage("argument to `arity` not supported, got INTEGER")},
+		{`arity("fn")`, errorMessage("argument to `arity` not supported, got STRING")},
+		{"arity()", errorMessage("wrong number of arguments. got=0, want=1)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMemoizeBuiltin(t *testing.T) {
+	fib := "let calls = 0; let fib = memoize(fn(n) { calls = calls + 1; n < 2 ? n : fib(n - 1) + fib(n - 2) });"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{fib + "fib(30)", "832040"},
+		{fib + "fib(30); calls", "31"},
+		{fib + "fib(30); fib(30); fib(20); calls", "31"},
+		{fib + "fib(90)", "2880067194370816120"},
+		{"let calls = 0; let f = memoize(fn(a, b) { calls = calls + 1; a + b }); f(1, 2); f(1, 2); f(2, 1); calls", "2"},
+		{`let calls = 0; let f = memoize(fn(x) { calls = calls + 1; len(x) }); f([1, 2]); f([1, 2]); f("ab"); calls`, "2"},
+		{"let calls = 0; let f = memoize(fn(x) { calls = calls + 1; x }); f(1); f(true); f(1.0); calls", "3"},
+		{"let calls = 0; let f = memoize(fn(g) { calls = calls + 1; g() }); f(fn() { 1 }); f(fn() { 1 }); calls", "2"},
+		{"let calls = 0; let f = memoize(fn(x) { calls = calls + 1; 1 / x }); let g = fn() { f(0) }; try(g, fn(e) { 0 }); try(g, fn(e) { 0 }); calls", "2"},
+		{"let f = memoize(fn(x) { 1 / x }); f(0)", "ERROR: 1:27: division by zero"},
+		{"let l = memoize(len); l([1, 2, 3])", "3"},
+		{"memoize(1)", "ERROR: 1:8: argument to `memoize` not supported, got INTEGER"},
+		{"memoize()", "ERROR: 1:8: wrong number of arguments. got=0, want=1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q to evaluate to %q, instead got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCharsAndBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`chars("abc")`, []string{"a", "b", "c"}},
+		{`chars("héllo, 世界")`, []string{"h", "é", "l", "l", "o", ",", " ", "世", "界"}},
+		{`chars("")`, []string{}},
+		{`len(chars("世界")) == len("世界")`, true},
+		{`bytes("abc")`, []int64{97, 98, 99}},
+		{`bytes("é世")`, []int64{0xc3, 0xa9, 0xe4, 0xb8, 0x96}},
+		{`bytes("")`, []int64{}},
+		{`chars(1)`, errorMessage("argument to `chars` not supported, got INTEGER")},
+		{`bytes(["a"])`, errorMessage("argument to `bytes` not supported, got ARRAY")},
+		{`bytes()`, errorMessage("wrong number of arguments. got=0, want=1)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case []string:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("Expected an Array object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("Expected %v elements, instead got %v", len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testStringObject(t, arr.Elements[i], el)
+			}
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("Expected an Array object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("Expected %v elements, instead got %v", len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testIntegerObject(t, arr.Elements[i], el)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`startsWith("monkey", "mon")`, true},
+		{`startsWith("monkey", "key")`, false},
+		{`startsWith("monkey", "")`, true},
+		{`startsWith("", "a")`, false},
+		{`endsWith("monkey", "key")`, true},
+		{`endsWith("monkey", "mon")`, false},
+		{`endsWith("世界", "界")`, true},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("banana", "an", "")`, "ba"},
+		{`replace("monkey", "x", "y")`, "monkey"},
+		{`replace("", "a", "b")`, ""},
+		{`startsWith("a", 1)`, errorMessage("argument to `startsWith` not supported, got INTEGER")},
+		{`endsWith(["a"], "a")`, errorMessage("argument to `endsWith` not supported, got ARRAY")},
+		{`replace("a", "a", null)`, errorMessage("argument to `replace` not supported, got NULL")},
+		{`replace("a", "a")`, errorMessage("wrong number of arguments. got=2, want=3)")},
+		{`startsWith("a")`, errorMessage("wrong number of arguments. got=1, want=2)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestUniqueBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`unique([1, 2, 1, 3, 2, 1])`, `[1, 2, 3]`},
+		{`unique(["b", "a", "b", "c", "a"])`, `["b", "a", "c"]`},
+		{`unique([1, "1", true, 1, "1", true])`, `[1, "1", true]`},
+		{`unique([[1, 2], [1, 2], [2, 1]])`, `[[1, 2], [2, 1]]`},
+		{`unique([null, null, 0])`, `[null, 0]`},
+		{`unique([])`, `[]`},
+		{`let a = [1, 1]; unique(a); a`, `[1, 1]`},
+		{`let f = fn() { 1 }; let g = fn() { 1 }; len(unique([f, g, f, g]))`, `2`},
+		{`len(unique([{"a": 1, "b": 2}, {"b": 2, "a": 1}, {"a": 2}]))`, `2`},
+		{`unique("aab")`, "ERROR: 1:7: argument to `unique` not supported, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCountBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`count([1, 2, 1, 3, 1], 1)`, `3`},
+		{`count(["a", "b", "a"], "a")`, `2`},
+		{`count([1, 2, 3], 4)`, `0`},
+		{`count([1, "1", true], 1)`, `1`},
+		{`count([[1], [1, 2], [1]], [1])`, `2`},
+		{`count([], null)`, `0`},
+		{`let f = fn() { 1 }; count([f, fn() { 1 }, f], f)`, `2`},
+		{`count([{"a": 1}, {"a": 2}, {"a": 1}], {"a": 1})`, `2`},
+		{`entries(countBy([1, 2, 3, 4, 5], fn(x) { x - x / 2 * 2 == 0 ? "even" : "odd" }))`, `[["odd", 3], ["even", 2]]`},
+		{`entries(countBy(["apple", "kiwi", "fig", "pear"], len))`, `[[5, 1], [4, 2], [3, 1]]`},
+		{`entries(countBy([], len))`, `[]`},
+		{`count("aab", "a")`, "ERROR: 1:6: argument to `count` not supported, got STRING"},
+		{`count([1])`, "ERROR: 1:6: wrong number of arguments. got=1, want=2)"},
+		{`countBy([1, 2], fn(x) { x + true })`, "ERROR: 1:27: type mismatch: INTEGER + BOOLEAN"},
+		{`countBy([1], fn(x) { fn() { x } })`, "ERROR: 1:8: unusable as hash key: FUNCTION"},
+		{`countBy(1, len)`, "ERROR: 1:8: argument to `countBy` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDeepEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 == 1.5", true},
+		{"1.5 != 2.5", true},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[] != []", false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"let a = [1]; let b = push(a, 2); a == b", false},
+		{"let a = [1]; let b = copy(a); a == b", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"let f = fn(x) { x }; f == f", true},
+		{"[fn() { 1 }] == [fn() { 1 }]", false},
+		{"len == len", true},
+		{"null == null", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestGroupByBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`entries(groupBy([1, 2, 3, 4, 5, 6, 7], fn(x) { x - x / 2 * 2 == 0 ? "even" : "odd" }))`,
+			`[["odd", [1, 3, 5, 7]], ["even", [2, 4, 6]]]`,
+		},
+		{`entries(groupBy(["apple", "kiwi", "fig", "pear"], len))`, `[[5, ["apple"]], [4, ["kiwi", "pear"]], [3, ["fig"]]]`},
+		{`entries(groupBy([1, 2, 3], fn(x) { true }))`, `[[true, [1, 2, 3]]]`},
+		{`entries(groupBy([], fn(x) { x }))`, `[]`},
+		{`let a = [3, 1]; groupBy(a, fn(x) { x }); a`, `[3, 1]`},
+		{`entries(groupBy([[1], [1], [2]], fn(x) { x }))`, `[[[1], [[1], [1]]], [[2], [[2]]]]`},
+		{`groupBy([1], fn(x) { fn() { x } })`, "ERROR: 1:8: unusable as hash key: FUNCTION"},
+		{`groupBy([1, 2], fn(x) { x + true })`, "ERROR: 1:27: type mismatch: INTEGER + BOOLEAN"},
+		{`groupBy({}, len)`, "ERROR: 1:8: argument to `groupBy` not supported, got HASH"},
+		{`groupBy([1])`, "ERROR: 1:8: wrong number of arguments. got=1, want=2)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTryBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try(fn() { 10 / 0 }, fn(e) { -1 })", -1},
+		{"try(fn() { 10 / 2 }, fn(e) { -1 })", 5},
+		{"let safeDiv = fn(a, b) { try(fn() { a / b }, fn(e) { 0 }) }; safeDiv(1, 0) + safeDiv(8, 2)", 4},
+		{"try(fn() { 1 + true }, fn(e) { e })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"try(fn() { 1 / 0 }, fn(e) { e + 1 })", errorMessage("division by zero")},
+		{"try(fn() { 1 / 0 }, fn() { 0 })", errorMessage("wrong number of arguments. got=1, want=0)")},
+		{"try(5, fn(e) { 0 })", errorMessage("argument to `try` not supported, got INTEGER")},
+		{"try(fn() { 1 / 0 }, 0)", errorMessage("argument to `try` not supported, got INTEGER")},
+		{"try(fn() { 1 }, 0)", errorMessage("argument to `try` not supported, got INTEGER")},
+		{"try(fn() { 1 })", errorMessage("wrong number of arguments. got=1, want=2)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTypeBuiltin(t *testing.T) {
+	examples := map[object.ObjectType]object.Object{
+		object.INTEGER_OBJ:      &object.Integer{Value: 1},
+		object.FLOAT_OBJ:        &object.Float{Value: 1.5},
+		object.BOOLEAN_OBJ:      TRUE,
+		object.NULL_OBJ:         NULL,
+		object.RETURN_VALUE_OBJ: &object.ReturnValue{Value: NULL},
+		object.ERROR_OBJ:        &object.Error{Message: "oops"},
+		object.FUNCTION_OBJ:     &object.Function{},
+		object.STRING_OBJ:       &object.String{Value: "a"},
+		object.BUILTIN_OBJ:      builtins["len"],
+		object.ARRAY_OBJ:        &object.Array{},
+		object.HASH_OBJ:         object.NewHash(),
+		object.BREAK_OBJ:        BREAK,
+		object.CONTINUE_OBJ:     CONTINUE,
+		object.QUOTE_OBJ:        &object.Quote{},
+		object.MACRO_OBJ:        &object.Macro{},
+	}
+
+	if len(examples) != len(object.ObjectTypes) {
+		t.Fatalf("Expected an example for each of the %d object types, got %d", len(object.ObjectTypes), len(examples))
+	}
+
+	for _, typ := range object.ObjectTypes {
+		example, ok := examples[typ]
+		if !ok {
+			t.Errorf("No example object for type %v", typ)
+			continue
+		}
+		if example.Type() != typ {
+			t.Errorf("Example for %v has type %v", typ, example.Type())
+		}
+		testStringObject(t, typeOf(example), typ.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type(1)`, "INTEGER"},
+		{`type("a")`, "STRING"},
+		{`type([1])`, "ARRAY"},
+		{`type({})`, "HASH"},
+		{`type(fn() {})`, "FUNCTION"},
+		{`type(len)`, "BUILTIN"},
+		{`type(null)`, "NULL"},
+		{`type(true)`, "BOOLEAN"},
+		{`type(quote(1))`, "QUOTE"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTypePredicates(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`isInt(1)`, true},
+		{`isInt("1")`, false},
+		{`isString("a")`, true},
+		{`isString(1)`, false},
+		{`isArray([1])`, true},
+		{`isArray({})`, false},
+		{`isHash({"a": 1})`, true},
+		{`isHash([])`, false},
+		{`isFunction(fn(x) { x })`, true},
+		{`isFunction(len)`, true},
+		{`isFunction(1)`, false},
+		{`isNull(if (false) { 1 })`, true},
+		{`isNull(0)`, false},
+		{`isBool(false)`, true},
+		{`isBool(0)`, false},
+		{`isInt()`, errorMessage("wrong number of arguments. got=0, want=1)")},
+		{`isHash({}, {})`, errorMessage("wrong number of arguments. got=2, want=1)")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`false < true`, true},
+		{`true > false`, true},
+		{`true < true`, false},
+		{`"a" < "b"`, true},
+		{`"b" > "abc"`, true},
+		{`"a" > "a"`, false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval(`[1] < [2]`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "unknown operator: ARRAY < ARRAY" {
+		t.Errorf("Expected error message to be %q, instead got %q", "unknown operator: ARRAY < ARRAY", errObj.Message)
+	}
+}
+
+func TestChainedComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`1 < 2 < 3`, true},
+		{`3 < 2 < 1`, false},
+		{`1 < 3 < 2`, false},
+		{`3 > 2 > 1`, true},
+		{`1 < 5 > 2`, true},
+		{`let x = 5; 1 < x < 10`, true},
+		{`let x = 50; 1 < x < 10`, false},
+		{`"a" < "b" < "c"`, true},
+		{`let n = 0; let f = fn() { n = n + 1; 5 }; 1 < f() < 10; n`, 1},
+		{`3 < 2 < foobar`, false},
+		{`1 < "a" < 3`, "type mismatch: INTEGER < STRING"},
+		{`(1 < 2) < 3`, "type mismatch: BOOLEAN < INTEGER"},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
-			testNullObject(t, evaluated)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
 		}
 	}
 }
 
-func TestHashLiterals(t *testing.T) {
-	input := `let two = "two";
-	{
-		"one": 10 - 9,
-		two: 1 + 1,
-		"thr" + "ee": 6 / 2,
-		4: 4,
-		true: 5,
-		false: 6
-	}`
-
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Hash)
+func TestMatchExpressions(t *testing.T) {
+	classify := `let classify = fn(x) {
+		match x {
+			0 => "zero",
+			-1 => "minus one",
+			"0" => "string zero",
+			true => "yes",
+			null => "nothing",
+			_ => "other",
+		}
+	};`
 
-	if !ok {
-		t.Fatalf("Expected a Hash, instead got %T(%+v)", evaluated, evaluated)
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{classify + `classify(0)`, "zero"},
+		{classify + `classify(-1)`, "minus one"},
+		{classify + `classify("0")`, "string zero"},
+		{classify + `classify(true)`, "yes"},
+		{classify + `classify(null)`, "nothing"},
+		{classify + `classify(false)`, "other"},
+		{classify + `classify(5)`, "other"},
+		{classify + `classify([0])`, "other"},
+		{`match 1 + 1 { 1 => 10, 2 => 20, 2 => 30 }`, 20},
+		{`match "b" { "a" => 1 }`, nil},
+		{`match 3 { }`, nil},
+		{`let n = 0; let f = fn() { n++; 2 }; match f() { 1 => 1, 2 => 2, 3 => 3 }; n`, 1},
+		{`match 1 { 2 => foobar, 1 => 10 }`, 10},
+		{`match foobar { _ => 1 }`, errorMessage("identifier not found: foobar")},
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():     1,
-		(&object.String{Value: "two"}).HashKey():     2,
-		(&object.String{Value: "three"}).HashKey():   3,
-		(&object.Integer{Value: int64(4)}).HashKey(): 4,
-		(&object.Boolean{Value: true}).HashKey():     5,
-		(&object.Boolean{Value: false}).HashKey():    6,
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
 	}
+}
 
-	if len(expected) != len(result.Pairs) {
-		t.Fatalf("The length are not equal, expected %v, received %v", len(expected), len(result.Pairs))
+func TestDoExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"do { 1; 2; 3 }", 3},
+		{"let x = do { let a = 2; let b = 3; a * b }; x", 6},
+		{"let a = 1; let b = do { let a = 10; a + 1 }; a + b", 12},
+		{"do { let inner = 1; inner }; inner", errorMessage("identifier not found: inner")},
+		{"let a = 1; do { a = 5 }; a", 5},
+		{"do { }", nil},
+		{"do { let y = 1; }", 1},
+		{"let f = fn() { let x = do { return 7; 8 }; x + 1 }; f()", 7},
+		{"let i = 0; while (true) { do { i++; if (i > 2) { break } } }; i", 3},
+		{"do { foobar; 1 }", errorMessage("identifier not found: foobar")},
+		{"let f = fn() { 1 + do { return 5 } }; f()", 5},
+		{"let f = fn() { do { return 5 } + 1 }; f()", 5},
+		{"let f = fn() { -do { return 5 } }; f()", 5},
+		{"let f = fn() { len(do { return 5 }); 6 }; f()", 5},
+		{"let f = fn() { [1, do { return 5 }][0] }; f()", 5},
+		{`let f = fn() { {"a": do { return 5 }} }; f()`, 5},
+		{"let f = fn(a = do { return 5 }) { a + 1 }; f()", 5},
+		{"1 + do { return 5 }", 5},
+		{"let i = 0; while (true) { i++; 1 + do { break } }; i", 1},
+		{"let n = 0; for (let i = 0; i < 3; i++) { n = n + do { continue } }; n", 0},
+		{"let f = fn() { 1 + do { break } }; f()", errorMessage("break outside of a loop")},
 	}
 
-	for key, value := range expected {
-		evaluatedPair, ok := result.Pairs[key]
-		if !ok {
-			t.Errorf("Value for key is absent")
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
 		}
-		testIntegerObject(t, evaluatedPair.Value, value)
 	}
 }
 
-func TestHashIndexExpressions(t *testing.T) {
+func TestDoExpressionReturnInArgument(t *testing.T) {
+	defer func(out io.Writer) { Stdout = out }(Stdout)
+	out := &bytes.Buffer{}
+	Stdout = out
+
+	evaluated := testEval(`let f = fn() { puts(do { return 5 }); puts("after"); 6 }; f()`)
+	testIntegerObject(t, evaluated, 5)
+	if out.String() != "" {
+		t.Errorf("Expected nothing to be printed, instead got %q", out.String())
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{
-			`{"foo": 5}["foo"]`,
-			5,
-		},
-		{
-			`{"foo": 5}["bar"]`,
-			nil,
-		},
-		{
-			`let key = "foo"; {"foo": 5}[key]`,
-			5,
-		},
-		{
-			`{}["foo"]`,
-			nil,
-		},
-		{
-			`{5: 5}[5]`,
-			5,
-		},
-		{
-			`{true: 5}[true]`,
-			5,
-		},
-		{
-			`{false: 5}[false]`,
-			5,
-		},
+		{"[1, 2, 3].len()", 3},
+		{`"hello".len()`, 5},
+		{"[1, 2, 3].push(4).len()", 4},
+		{"[3, 1, 2].sort().first()", 1},
+		{"let double = fn(x) { x * 2 }; 5.double().double()", 20},
+		{"let sub = fn(a, b) { a - b }; 10.sub(3)", 7},
+		{"[1, 2, 3].len() == 3", true},
+		{"1.len()", errorMessage("argument to `len` not supported, got INTEGER")},
+		{"1.foobar()", errorMessage("identifier not found: foobar")},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
-			testNullObject(t, evaluated)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
 		}
 	}
 }
 
-func testNullObject(t *testing.T, obj object.Object) bool {
-	if obj != NULL {
-		t.Errorf("Expected object to be NULL, instead got %T (%+v)", obj, obj)
-		return false
+func TestPipeOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3] |> reverse |> len", 3},
+		{"[1, 2, 3] |> reverse |> head", 3},
+		{"let double = fn(x) { x * 2 }; 5 |> double |> double", 20},
+		{"let add = fn(a, b) { a + b }; 1 |> add(10)", 11},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(1) |> sub(2)", 7},
+		{"2 + 3 |> fn(x) { x * x }", 25},
+		{"[1, 2, 3] |> len == 3", true},
+		{"let x = [1] |> push(2) |> len; x", 2},
+		{"1 |> foobar", errorMessage("identifier not found: foobar")},
+		{"1 |> len", errorMessage("argument to `len` not supported, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
 	}
-	return true
 }
 
-func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
-	result, ok := obj.(*object.Integer)
+func TestPostfixExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let i = 5; i++`, 5},
+		{`let i = 5; i++; i`, 6},
+		{`let i = 5; i--`, 5},
+		{`let i = 5; i--; i`, 4},
+		{`let i = 0; let sum = 0; while (i < 5) { sum = sum + i++ }; sum`, 10},
+		{`let i = 3; while (i > 0) { i-- }; i`, 0},
+		{`let n = 0; for (let i = 0; i < 4; i++) { n++ }; n`, 4},
+		{`let a = [1, 2]; a[1]++; a`, []int64{1, 3}},
+		{`let a = [1, 2]; a[0]--`, 1},
+		{`let h = {"x": 1}; h["x"]++; h["x"]`, 2},
+		{`let x = 1; let f = fn() { x++ }; f(); f(); x`, 3},
+		{`foo++`, "identifier not found: foo"},
+		{`let s = "a"; s++`, "unknown operator: STRING++"},
+		{`let h = {}; h["x"]--`, "unknown operator: NULL--"},
+		{`let a = [1]; a["x"]++`, "array index must be INTEGER, got STRING"},
+	}
 
-	if !ok {
-		t.Errorf("Expected object to be Integer, instead got %T (%v)", obj, obj)
-		return false
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("Expected an Array object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			for i, el := range expected {
+				testIntegerObject(t, arr.Elements[i], el)
+			}
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
 	}
+}
 
-	if result.Value != expected {
-		t.Errorf("Expected integer value to be %v, instead got %v", expected, result.Value)
-		return false
+func TestHashIteration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let total = 0; each({"a": 1, "b": 2, "c": 3}, fn(k, v) { total = total + v }); total`, 6},
+		{`let ks = ""; each({"b": 1, "a": 2}, fn(k, v) { ks = ks + k }); ks`, `"ba"`},
+		{`each({}, fn(k, v) { foobar })`, nil},
+		{`each({"a": 1}, fn(k, v) { 1 })`, nil},
+		{`let h = mapValues({"a": 1, "b": 2}, fn(v) { v * 10 }); h["a"] + h["b"]`, 30},
+		{`keys(mapValues({"b": 1, "a": 2}, fn(v) { v }))`, `["b", "a"]`},
+		{`let h = {"a": 1}; mapValues(h, fn(v) { v + 1 }); h["a"]`, 1},
+		{`each({"a": 1}, fn(k, v) { v + true })`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`mapValues({"a": 1}, fn(v) { foobar })`, errorMessage("identifier not found: foobar")},
+		{`each({"a": 1}, fn(v) { v })`, errorMessage("wrong number of arguments. got=2, want=1)")},
+		{`each([1], fn(k, v) { v })`, errorMessage("argument to `each` not supported, got ARRAY")},
+		{`mapValues({"a": 1}, 5)`, errorMessage("not a function: INTEGER")},
 	}
 
-	return true
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("Expected %s, instead got %s", expected, evaluated.Inspect())
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("Expected an Error object, instead got %T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("Expected error message to be %q, instead got %q", expected, errObj.Message)
+			}
+		}
+	}
 }
 
-func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
-	result, ok := obj.(*object.Boolean)
+func TestLiteralErrorPropagation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[1, foobar, 3]`, "identifier not found: foobar"},
+		{`[foobar]`, "identifier not found: foobar"},
+		{`[1, 2, 3 + true]`, "type mismatch: INTEGER + BOOLEAN"},
+		{`[[1, foobar]]`, "identifier not found: foobar"},
+		{`{"a": 1, "b": bar}`, "identifier not found: bar"},
+		{`{"a": 1, bar: 2}`, "identifier not found: bar"},
+		{`{"a": 1, "b": {"c": bar}}`, "identifier not found: bar"},
+		{`{"a": 1, fn() {}: 2}`, "Can't use expression of type FUNCTION as hash key"},
+		{`let f = fn(a, b) { a }; f(1, foobar)`, "identifier not found: foobar"},
+		{`let a = [1, 2]; let a = [3, foobar]; a`, "identifier not found: foobar"},
+	}
 
-	if !ok {
-		t.Errorf("Expected object to be Boolean, instead got %T", obj)
-		return false
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+		}
 	}
+}
 
-	if result.Value != expected {
-		t.Errorf("Expected object value to be %v, instead got %v", expected, result.Value)
-		return false
+func TestCachedIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"-128", -128},
+		{"255", 255},
+		{"256", 256},
+		{"-129 + 1", -128},
+		{"let a = 100; a + a + 55", 255},
+		{"let a = 200; a + a", 400},
 	}
 
-	return true
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	if testEval("1 + 2") != testEval("3") {
+		t.Errorf("Expected small integers to be interned")
+	}
+	if testEval("1000 + 2000") == testEval("3000") {
+		t.Errorf("Expected large integers not to be interned")
+	}
+	testBooleanObject(t, testEval("let a = 300; let b = 300; a == b"), true)
 }
 
-func testEval(input string) object.Object {
+func BenchmarkRecursiveCalls(b *testing.B) {
+	input := `
+	let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+	fib(15);`
+
 	l := lexer.New(input)
 	p := parser.New(l)
-	env := object.NewEnvironment()
+	program := p.ParseProgram()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
+
+func BenchmarkIntegerArithmetic(b *testing.B) {
+	input := `
+	let sum = 0;
+	for (let i = 0; i < 100; i = i + 1) {
+		sum = (sum + i * 2) - i - i;
+	}
+	sum;`
 
+	l := lexer.New(input)
+	p := parser.New(l)
 	program := p.ParseProgram()
 
-	return Eval(program, env)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
 }
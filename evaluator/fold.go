@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"strconv"
+
+	"monkey-interpreter/ast"
+	"monkey-interpreter/object"
+	"monkey-interpreter/token"
+)
+
+// FoldConstants returns a copy of node where prefix and infix operations on
+// integer, boolean and string literals are replaced by their result, so
+// `2 + 3 * 4` becomes `14`. Operations that would fail at runtime, like a
+// division by zero, are left alone so the error still happens when (and
+// if) they're evaluated. Arguments to quote are never folded, so the pass
+// should run after macro expansion
+func FoldConstants(node ast.Node) ast.Node {
+	// Modify visits quote calls in the same order on both passes, so the
+	// first one records an unfolded copy of each for the second to put back
+	quoted := []ast.Node{}
+	ast.Modify(node, func(node ast.Node) ast.Node {
+		if isQuoteCall(node) {
+			quoted = append(quoted, node)
+		}
+		return node
+	})
+
+	return ast.Modify(node, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			// Modify has already folded the copy it passes in
+			if isQuoteCall(node) {
+				original := quoted[0]
+				quoted = quoted[1:]
+				return original
+			}
+
+		case *ast.PrefixExpression:
+			right, ok := literalValue(node.Right)
+			if !ok || (node.Operator == "!" && right.Type() != object.BOOLEAN_OBJ) {
+				return node
+			}
+			if folded, ok := literalNode(evalPrefixExpression(node.Operator, right), node.Token); ok {
+				return folded
+			}
+
+		case *ast.InfixExpression:
+			left, ok := literalValue(node.Left)
+			if !ok {
+				return node
+			}
+			right, ok := literalValue(node.Right)
+			if !ok {
+				return node
+			}
+			if folded, ok := literalNode(evalInfixExpression(node.Operator, left, right), node.Token); ok {
+				return folded
+			}
+		}
+		return node
+	})
+}
+
+// literalValue evaluates the literals FoldConstants can fold
+func literalValue(node ast.Expression) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return newInteger(node.Value), true
+	case *ast.BooleanExpression:
+		return nativeBoolToBooleanObject(node.Value), true
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}, true
+	}
+	return nil, false
+}
+
+// literalNode turns the result of a folded operation back into a literal,
+// positioned at tok
+func literalNode(obj object.Object, tok token.Token) (ast.Expression, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		tok.Type, tok.Literal = token.INT, strconv.FormatInt(obj.Value, 10)
+		return &ast.IntegerLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Boolean:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if obj.Value {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		return &ast.BooleanExpression{Token: tok, Value: obj.Value}, true
+	case *object.String:
+		tok.Type, tok.Literal = token.STRING, obj.Value
+		return &ast.StringLiteral{Token: tok, Value: obj.Value}, true
+	}
+	return nil, false
+}
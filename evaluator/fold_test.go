@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+
+	"monkey-interpreter/ast"
+	"monkey-interpreter/lexer"
+	"monkey-interpreter/object"
+	"monkey-interpreter/parser"
+	"monkey-interpreter/token"
+)
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 + 3 * 4", "14"},
+		{"(1 + 2) * (3 - 4)", "-3"},
+		{"-5", "-5"},
+		{"-(-5)", "5"},
+		{"2 ** 10 > 1000", "true"},
+		{"1 == 2", "false"},
+		{"!true", "false"},
+		{"!!false == false", "true"},
+		{"true != false", "true"},
+		{`"foo" + "bar"`, `"foobar"`},
+		{`"a" + "b" + "c"`, `"abc"`},
+		{"let f = fn(x) { x * (2 + 3) };", "let f = fn(x){(x * 5)};"},
+		{"x + 1 + 2", "((x + 1) + 2)"},
+		{"x + (1 + 2)", "(x + 3)"},
+		{"[1 + 1, len(2 * 3)]", "[2, len(6)]"},
+		{"if (1 < 2) { 3 * 3 } else { 4 }", "iftrue 9else 4"},
+		{"1 / 0", "(1 / 0)"},
+		{"2 * (1 / 0)", "(2 * (1 / 0))"},
+		{"9223372036854775807 + 1", "(9223372036854775807 + 1)"},
+		{"2 ** -1", "(2 ** -1)"},
+		{"1 + true", "(1 + true)"},
+		{`"a" - "b"`, `("a" - "b")`},
+		{"!0", "(!0)"},
+		{"1 < 2 < 3", "(1 < 2 < 3)"},
+		{"quote(1 + 2) + 3", "(quote((1 + 2)) + 3)"},
+		{"f(quote(1 + 2), 3 + 4)", "f(quote((1 + 2)), 7)"},
+		{"quote(quote(1 + 2) + (3 + 4)) + (5 + 6)", "(quote((quote((1 + 2)) + (3 + 4))) + 11)"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		folded := FoldConstants(program)
+
+		if folded.String() != tt.expected {
+			t.Errorf("Expected %q to fold to %q, instead got %q", tt.input, tt.expected, folded.String())
+		}
+	}
+}
+
+func TestFoldConstantsPreservesResults(t *testing.T) {
+	tests := []string{
+		"2 + 3 * 4",
+		"let f = fn(x) { x * (2 + 3) - 10 / 2 }; f(4)",
+		`let greet = fn(name) { "hello " + "there " + name }; greet("you")`,
+		"if (2 > 1 == true) { 10 - 20 } else { 0 }",
+		"let x = 3; x * 2 ** 3 + -(4 - 1)",
+		"1 / 0",
+		"2 * (1 / 0)",
+		"9223372036854775807 + 1",
+		`quote(1 + 2)`,
+		"!true == !!false",
+	}
+
+	for _, input := range tests {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		expected := Eval(program, object.NewEnvironment())
+		actual := Eval(FoldConstants(program), object.NewEnvironment())
+
+		if actual.Inspect() != expected.Inspect() {
+			t.Errorf("Expected %q to evaluate to %q after folding, instead got %q",
+				input, expected.Inspect(), actual.Inspect())
+		}
+	}
+
+	// The original tree is left untouched
+	program := parser.New(lexer.New("1 + 2")).ParseProgram()
+	FoldConstants(program)
+	if _, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression); !ok {
+		t.Errorf("Expected the original tree not to be folded, instead got %v", program)
+	}
+}
+
+func TestFoldConstantsSynthesizedQuoteCalls(t *testing.T) {
+	// Nodes built without a parser have no tokens to tell them apart
+	quoteCall := func(left, right int64) ast.Statement {
+		return &ast.ExpressionStatement{Expression: &ast.CallExpression{
+			Function: &ast.Identifier{Value: "quote"},
+			Arguments: []ast.Expression{&ast.InfixExpression{
+				Left:     &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: fmt.Sprint(left)}, Value: left},
+				Operator: "+",
+				Right:    &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: fmt.Sprint(right)}, Value: right},
+			}},
+		}}
+	}
+	program := &ast.Program{Statements: []ast.Statement{quoteCall(1, 2), quoteCall(3, 4)}}
+
+	folded := FoldConstants(program)
+	if folded.String() != "quote((1 + 2))quote((3 + 4))" {
+		t.Errorf("Expected quote calls to be left alone, instead got %q", folded.String())
+	}
+}
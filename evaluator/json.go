@@ -0,0 +1,196 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"monkey-interpreter/object"
+)
+
+func toJSON(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	if err := encodeJSON(&sb, args[0]); err != nil {
+		return err
+	}
+	return &object.String{Value: sb.String()}
+}
+
+func encodeJSON(sb *strings.Builder, obj object.Object) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		sb.WriteString(strconv.FormatInt(obj.Value, 10))
+
+	case *object.BigInt:
+		sb.WriteString(obj.Value.String())
+
+	case *object.Float:
+		if math.IsInf(obj.Value, 0) || math.IsNaN(obj.Value) {
+			return newError("unsupported JSON value: %v", obj.Inspect())
+		}
+		sb.WriteString(strconv.FormatFloat(obj.Value, 'g', -1, 64))
+
+	case *object.Boolean:
+		sb.WriteString(strconv.FormatBool(obj.Value))
+
+	case *object.Null:
+		sb.WriteString("null")
+
+	case *object.String:
+		writeJSONString(sb, obj.Value)
+
+	case *object.Array:
+		sb.WriteString("[")
+		for i, el := range obj.Elements {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			if err := encodeJSON(sb, el); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("]")
+
+	case *object.Hash:
+		// JSON keys are always strings, so integer keys are written as their
+		// decimal form. A hash with both 1 and "1" as keys can't be written
+		// without losing one of them
+		keys := make([]string, 0, len(obj.Pairs))
+		values := make(map[string]object.Object, len(obj.Pairs))
+		for _, pair := range obj.OrderedPairs() {
+			var key string
+			switch k := pair.Key.(type) {
+			case *object.String:
+				key = k.Value
+			case *object.Integer:
+				key = strconv.FormatInt(k.Value, 10)
+			default:
+				return newError("unusable as JSON key: %v", pair.Key.Type())
+			}
+			if _, ok := values[key]; ok {
+				return newError("duplicate JSON key: %v", key)
+			}
+			keys = append(keys, key)
+			values[key] = pair.Value
+		}
+
+		sb.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			writeJSONString(sb, key)
+			sb.WriteString(":")
+			if err := encodeJSON(sb, values[key]); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("}")
+
+	default:
+		return newError("argument to `json` not supported, got %v", obj.Type())
+	}
+
+	return nil
+}
+
+func writeJSONString(sb *strings.Builder, s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	sb.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+func parseJSON(args ...object.Object) object.Object {
+	if err := checkArgumentCount(args, 1); err != nil {
+		return err
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return unsupportedArgument("parseJSON", args[0])
+	}
+
+	// Decoding into a RawMessage validates the document. It's then walked
+	// token by token, as decoding into a map would lose the key order
+	dec := json.NewDecoder(strings.NewReader(str.Value))
+
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return newError("invalid JSON: %v", err)
+	}
+	if dec.More() {
+		return newError("invalid JSON: unexpected data after top-level value")
+	}
+
+	tokens := json.NewDecoder(bytes.NewReader(raw))
+	tokens.UseNumber()
+	return decodeJSON(tokens)
+}
+
+func decodeJSON(dec *json.Decoder) object.Object {
+	tok, err := dec.Token()
+	if err != nil {
+		return newError("invalid JSON: %v", err)
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return NULL
+
+	case bool:
+		return nativeBoolToBooleanObject(tok)
+
+	case string:
+		return &object.String{Value: tok}
+
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return newInteger(i)
+		}
+		f, err := tok.Float64()
+		if err != nil {
+			return newError("invalid JSON number: %v", tok)
+		}
+		return &object.Float{Value: f}
+
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for dec.More() {
+				el := decodeJSON(dec)
+				if isError(el) {
+					return el
+				}
+				elements = append(elements, el)
+			}
+			dec.Token() // closing ]
+			return &object.Array{Elements: elements}
+		}
+
+		hash := object.NewHash()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return newError("invalid JSON: %v", err)
+			}
+			key := &object.String{Value: keyTok.(string)}
+			val := decodeJSON(dec)
+			if isError(val) {
+				return val
+			}
+			hash.Set(key.HashKey(), object.HashPair{Key: key, Value: val})
+		}
+		dec.Token() // closing }
+		return hash
+	}
+
+	return NULL
+}
@@ -0,0 +1,157 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"monkey-interpreter/ast"
+	"monkey-interpreter/object"
+	"monkey-interpreter/token"
+)
+
+func quote(node ast.Node, env *object.Environment) object.Object {
+	node, err := evalUnquoteCalls(node, env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+// evalUnquoteCalls replaces unquote calls with the values of their
+// arguments, stopping at the first one that fails
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+	node := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if err != nil || !isUnquoteCall(node) {
+			return node
+		}
+
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		if len(call.Arguments) != 1 {
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if unquotedErr, ok := unquoted.(*object.Error); ok {
+			err = unquotedErr
+			return node
+		}
+		converted, convertErr := convertObjectToASTNode(unquoted)
+		if convertErr != nil {
+			err = convertErr
+			return node
+		}
+		return converted
+	})
+	return node, err
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	return callExpression.Function.TokenLiteral() == "unquote"
+}
+
+// isQuoteCall looks at the called identifier rather than its token, so it
+// also recognizes quote calls in trees built without a parser
+func isQuoteCall(node ast.Node) bool {
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	function, ok := callExpression.Function.(*ast.Identifier)
+	return ok && function.Value == "quote"
+}
+
+// convertObjectToASTNode turns a value back into the literal that produces
+// it. Values without a literal, like functions, are errors
+func convertObjectToASTNode(obj object.Object) (ast.Node, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{
+			Type:    token.INT,
+			Literal: fmt.Sprintf("%d", obj.Value),
+		}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, nil
+
+	case *object.BigInt:
+		t := token.Token{Type: token.INT, Literal: obj.Value.String()}
+		return &ast.BigIntegerLiteral{Token: t, Value: obj.Value}, nil
+
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect()}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, nil
+
+	case *object.Boolean:
+		var t token.Token
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		} else {
+			t = token.Token{Type: token.FALSE, Literal: "false"}
+		}
+		return &ast.BooleanExpression{Token: t, Value: obj.Value}, nil
+
+	case *object.Null:
+		return &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null"}}, nil
+
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, nil
+
+	case *object.Array:
+		elements := make([]ast.Expression, len(obj.Elements))
+		for i, element := range obj.Elements {
+			node, err := convertObjectToExpression(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = node
+		}
+		t := token.Token{Type: token.LBRACKET, Literal: "["}
+		return &ast.ArrayLiteral{Token: t, Elements: elements}, nil
+
+	case *object.Hash:
+		t := token.Token{Type: token.LBRACE, Literal: "{"}
+		hash := &ast.HashLiteral{Token: t, Pairs: map[ast.Expression]ast.Expression{}}
+		for _, pair := range obj.OrderedPairs() {
+			key, err := convertObjectToExpression(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := convertObjectToExpression(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			hash.Keys = append(hash.Keys, key)
+			hash.Pairs[key] = value
+		}
+		return hash, nil
+
+	case *object.Quote:
+		return obj.Node, nil
+
+	default:
+		return nil, unsupportedArgument("unquote", obj)
+	}
+}
+
+// convertObjectToExpression is convertObjectToASTNode for values that end
+// up inside an array or hash literal
+func convertObjectToExpression(obj object.Object) (ast.Expression, *object.Error) {
+	node, err := convertObjectToASTNode(obj)
+	if err != nil {
+		return nil, err
+	}
+	expression, ok := node.(ast.Expression)
+	if !ok {
+		return nil, unsupportedArgument("unquote", obj)
+	}
+	return expression, nil
+}
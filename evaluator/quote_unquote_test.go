@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey-interpreter/object"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar)`, `foobar`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		if quote.Node == nil {
+			t.Fatalf("quote.Node is nil")
+		}
+
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), tt.expected)
+		}
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{`let foobar = 8; quote(foobar)`, `foobar`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true))`, `true`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`quote(unquote("foo"))`, `"foo"`},
+		{`quote(unquote(null))`, `null`},
+		{`quote(f(unquote(1 + 1)))`, `f(2)`},
+		{`quote(1 + unquote([1, 2]))`, `(1 + [1, 2])`},
+		{`quote(unquote([1, [true, "a"], quote(x)]))`, `[1, [true, "a"], x]`},
+		{`quote(unquote({"a": 1, 2: [3]}))`, "{\n\"a\":1,2:[3]\n}"},
+		{`quote(unquote(1.5))`, `1.5`},
+		{
+			`let quotedInfixExpression = quote(4 + 4);
+			quote(unquote(4 + 4) + unquote(quotedInfixExpression))`,
+			`(8 + (4 + 4))`,
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		if quote.Node == nil {
+			t.Fatalf("quote.Node is nil")
+		}
+
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), tt.expected)
+		}
+	}
+}
+
+func TestQuoteUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(foo))`, "identifier not found: foo"},
+		{`quote(1 + unquote(1 / 0))`, "division by zero"},
+		{`quote(unquote(fn() { 1 }))`, "argument to `unquote` not supported, got FUNCTION"},
+		{`quote(unquote([1, len]))`, "argument to `unquote` not supported, got BUILTIN"},
+		{`quote(unquote({"f": fn() { 1 }}))`, "argument to `unquote` not supported, got FUNCTION"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("Expected an Error object for %s, instead got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("Expected error message to be %q, instead got %q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestQuoteUnquoteBigIntegers(t *testing.T) {
+	defer func(big bool) { BigIntegers = big }(BigIntegers)
+	BigIntegers = true
+
+	evaluated := testEval(`quote(unquote(2 ** 64) + 1)`)
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+	}
+	if quote.Node.String() != "(18446744073709551616 + 1)" {
+		t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), "(18446744073709551616 + 1)")
+	}
+}
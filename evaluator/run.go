@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"monkey-interpreter/lexer"
+	"monkey-interpreter/object"
+	"monkey-interpreter/parser"
+)
+
+// Run parses, macro-expands and evaluates source in a fresh environment.
+// If the source doesn't parse, or a macro fails to expand, the result is nil
+// and the errors are returned instead. Runtime errors are returned as
+// *object.Error results
+func Run(source string) (object.Object, []string) {
+	p := newParser(source)
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return nil, errors
+	}
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	if result := Eval(expanded, object.NewEnvironment()); result != nil {
+		return result, nil
+	}
+	return NULL, nil
+}
+
+// EvalStream evaluates the program read by p one statement at a time, so
+// the whole program never has to be held in memory and side effects happen
+// as soon as each statement is parsed. Evaluation stops at the first parser
+// error, which is returned along with any others found in the same
+// statement. Macros aren't expanded, and functions aren't hoisted since
+// the statements defining them may not have been read yet
+func EvalStream(p *parser.Parser, env *object.Environment) (object.Object, []string) {
+	var result object.Object
+
+	for {
+		statement, ok := p.NextStatement()
+		if errors := p.Errors(); len(errors) != 0 {
+			return nil, errors
+		}
+		if !ok {
+			break
+		}
+
+		var done bool
+		if result, done = evalProgramStatement(statement, env); done {
+			return result, nil
+		}
+	}
+
+	if result == nil {
+		return NULL, nil
+	}
+	return result, nil
+}
+
+// newParser returns a parser for source that accepts big integer literals
+// when BigIntegers is on
+func newParser(source string) *parser.Parser {
+	p := parser.New(lexer.New(source))
+	p.BigIntegers = BigIntegers
+	return p
+}
@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey-interpreter/lexer"
+	"monkey-interpreter/object"
+	"monkey-interpreter/parser"
+)
+
+func TestRun(t *testing.T) {
+	result, errors := Run("1 + 2")
+	if len(errors) != 0 {
+		t.Fatalf("Expected no parser errors, instead got %v", errors)
+	}
+	testIntegerObject(t, result, 3)
+
+	result, _ = Run("let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) }; unless(false, 1, 2)")
+	testIntegerObject(t, result, 1)
+
+	result, _ = Run("")
+	testNullObject(t, result)
+
+	result, errors = Run("foobar")
+	if len(errors) != 0 {
+		t.Fatalf("Expected no parser errors, instead got %v", errors)
+	}
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "identifier not found: foobar" {
+		t.Errorf("Expected an identifier not found error, instead got %+v", result)
+	}
+}
+
+func TestRunParserErrors(t *testing.T) {
+	result, errors := Run("let x 5; 1 +")
+	if result != nil {
+		t.Errorf("Expected no result for malformed input, instead got %+v", result)
+	}
+
+	expected := []string{
+		"1:7: Expected token =, instead got INT",
+		"1:13: No prefix parse function found for EOF",
+	}
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected %d parser errors, instead got %v", len(expected), errors)
+	}
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("Expected error %q, instead got %q", msg, errors[i])
+		}
+	}
+}
+
+func TestRunMacroErrors(t *testing.T) {
+	result, errors := Run("let m = macro(x) { 1 }; m(2)")
+	if result != nil {
+		t.Errorf("Expected no result for a failed macro expansion, instead got %+v", result)
+	}
+	if len(errors) != 1 || errors[0] != "1:26: macro m must return a quote, got INTEGER" {
+		t.Errorf("Expected a macro expansion error, instead got %v", errors)
+	}
+}
+
+func TestEvalStream(t *testing.T) {
+	input := `
+	let a = 1;
+	let add = fn(x, y) { x + y };
+	let b = add(a, 2);
+	let list = [a, b];
+	let h = {"sum": add(a, b)};
+	for (let i = 0; i < 3; i++) { a = a + i }
+	add(a, b);`
+
+	batchEnv := object.NewEnvironment()
+	batchResult := Eval(parser.New(lexer.New(input)).ParseProgram(), batchEnv)
+
+	streamEnv := object.NewEnvironment()
+	streamResult, errors := EvalStream(parser.New(lexer.New(input)), streamEnv)
+	if len(errors) != 0 {
+		t.Fatalf("Expected no parser errors, instead got %v", errors)
+	}
+
+	if streamResult.Inspect() != batchResult.Inspect() {
+		t.Errorf("Expected result %s, instead got %s", batchResult.Inspect(), streamResult.Inspect())
+	}
+
+	batchKeys, streamKeys := batchEnv.Keys(), streamEnv.Keys()
+	if len(batchKeys) != len(streamKeys) {
+		t.Fatalf("Expected bindings %v, instead got %v", batchKeys, streamKeys)
+	}
+	for i, key := range batchKeys {
+		if streamKeys[i] != key {
+			t.Fatalf("Expected bindings %v, instead got %v", batchKeys, streamKeys)
+		}
+		batchVal, _ := batchEnv.Get(key)
+		streamVal, _ := streamEnv.Get(key)
+		if batchVal.Inspect() != streamVal.Inspect() {
+			t.Errorf("Expected %s to be %s, instead got %s", key, batchVal.Inspect(), streamVal.Inspect())
+		}
+	}
+}
+
+func TestEvalStreamStops(t *testing.T) {
+	env := object.NewEnvironment()
+	result, errors := EvalStream(parser.New(lexer.New("let a = 1; let b = ; let c = 3;")), env)
+	if result != nil || len(errors) != 1 || errors[0] != "1:20: No prefix parse function found for ;" {
+		t.Errorf("Expected evaluation to stop at the parser error, got %v, %v", result, errors)
+	}
+	if _, ok := env.Get("a"); !ok {
+		t.Errorf("Expected statements before the parser error to be evaluated")
+	}
+	if _, ok := env.Get("c"); ok {
+		t.Errorf("Expected statements after the parser error not to be evaluated")
+	}
+
+	env = object.NewEnvironment()
+	result, _ = EvalStream(parser.New(lexer.New("let a = 1; return a + 1; let c = 3;")), env)
+	testIntegerObject(t, result, 2)
+	if _, ok := env.Get("c"); ok {
+		t.Errorf("Expected statements after a top-level return not to be evaluated")
+	}
+
+	result, _ = EvalStream(parser.New(lexer.New("let a = 1; foobar; let c = 3;")), object.NewEnvironment())
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "identifier not found: foobar" {
+		t.Errorf("Expected an identifier not found error, instead got %+v", result)
+	}
+
+	result, _ = EvalStream(parser.New(lexer.New("")), object.NewEnvironment())
+	testNullObject(t, result)
+}
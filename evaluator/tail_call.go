@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"monkey-interpreter/ast"
+	"monkey-interpreter/object"
+)
+
+// tailCall holds the arguments of a call a function makes to itself as its
+// final action. applyFunction runs such calls in a loop instead of
+// recursing, so tail-recursive functions don't grow the Go stack or count
+// towards MaxCallDepth
+type tailCall struct {
+	args []object.Object
+}
+
+// evalTailBlock evaluates the body of self, or a block whose value becomes
+// the result of self
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment, self *object.Function) (object.Object, *tailCall) {
+	var result object.Object
+	for i, statement := range block.Statements {
+		if i == len(block.Statements)-1 {
+			return evalTailStatement(statement, env, self)
+		}
+
+		result = Eval(statement, env)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+				return result, nil
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func evalTailStatement(statement ast.Statement, env *object.Environment, self *object.Function) (object.Object, *tailCall) {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		switch statement.Expression.(type) {
+		case *ast.IfExpression, *ast.TernaryExpression, *ast.CallExpression:
+			return evalTailExpression(statement.Expression, env, self)
+		}
+
+	case *ast.ReturnStatement:
+		if statement.ReturnValue == nil {
+			break
+		}
+		val, tail := evalTailExpression(statement.ReturnValue, env, self)
+		if tail != nil || isAbrupt(val) {
+			return val, tail
+		}
+		return &object.ReturnValue{Value: val}, nil
+	}
+
+	return Eval(statement, env), nil
+}
+
+func evalTailExpression(node ast.Expression, env *object.Environment, self *object.Function) (object.Object, *tailCall) {
+	switch node := node.(type) {
+	case *ast.IfExpression:
+		condition := Eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition, nil
+		}
+		if isTruthy(condition) {
+			return evalTailBlock(node.Consequence, env, self)
+		} else if node.Alternative != nil {
+			return evalTailBlock(node.Alternative, env, self)
+		}
+		return NULL, nil
+
+	case *ast.TernaryExpression:
+		condition := Eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition, nil
+		}
+		if isTruthy(condition) {
+			return evalTailExpression(node.Consequence, env, self)
+		}
+		return evalTailExpression(node.Alternative, env, self)
+
+	case *ast.CallExpression:
+		ident, ok := node.Function.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		if fn, ok := env.Get(ident.Value); !ok || fn != self {
+			break
+		}
+
+		args, err := evalExpressions(node.Arguments, env)
+		if err != nil {
+			return err, nil
+		}
+		return nil, &tailCall{args: args}
+	}
+
+	return Eval(node, env), nil
+}
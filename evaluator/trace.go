@@ -0,0 +1,26 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"monkey-interpreter/ast"
+	"monkey-interpreter/object"
+)
+
+// Trace, if set, gets a line for every node Eval evaluates, indented by
+// how deeply it's nested in the evaluation, so the order things run in can
+// be followed. It's nil, and tracing costs nothing, by default
+var Trace io.Writer
+
+var traceDepth int
+
+func traceEval(node ast.Node, env *object.Environment) object.Object {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	fmt.Fprintf(Trace, "%s%s\n", strings.Repeat("  ", traceDepth), name)
+
+	traceDepth++
+	defer func() { traceDepth-- }()
+	return eval(node, env)
+}
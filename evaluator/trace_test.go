@@ -0,0 +1,34 @@
+package evaluator
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	out := &bytes.Buffer{}
+	defer func() { Trace = nil }()
+	Trace = out
+
+	testIntegerObject(t, testEval("let x = 2; -x + 3"), 1)
+
+	expected := `Program
+  LetStatement
+    IntegerLiteral
+  ExpressionStatement
+    InfixExpression
+      PrefixExpression
+        Identifier
+      IntegerLiteral
+`
+	if out.String() != expected {
+		t.Errorf("Expected trace %q, instead got %q", expected, out.String())
+	}
+
+	out.Reset()
+	Trace = nil
+	testEval("1 + 2")
+	if out.Len() != 0 {
+		t.Errorf("Expected no trace output once disabled, instead got %q", out.String())
+	}
+}
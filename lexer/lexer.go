@@ -1,47 +1,109 @@
 package lexer
 
 import (
+	"unicode"
+	"unicode/utf8"
+
 	"monkey-interpreter/token"
 )
 
+// Lexer reads its input as UTF-8. Positions are byte offsets into the input,
+// while columns count runes
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
+	line         int
+	column       int
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
+}
+
+// operandAfterPeek reports whether an operand starts on the same line after
+// the peeked character. It keeps 5--3 lexing as 5 - -3 rather than as a
+// decrement
+func (l *Lexer) operandAfterPeek() bool {
+	_, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	for _, ch := range l.input[l.readPosition+width:] {
+		if ch == ' ' || ch == '\t' {
+			continue
+		}
+		return isLetter(ch) || isDigit(ch) || ch == '(' || ch == '[' || ch == '"' || ch == '!'
+	}
+	return false
 }
 
-func (l *Lexer) readNumber() string {
+// readDigits reads digits that may be grouped with single underscores, e.g.
+// 1_000_000. ok is false if an underscore is doubled or trails the digits
+func (l *Lexer) readDigits() (ok bool) {
+	ok = true
+	for isDigit(l.ch) || l.ch == '_' {
+		if l.ch == '_' && !isDigit(l.peekChar()) {
+			ok = false
+		}
+		l.readChar()
+	}
+	return ok
+}
+
+// readNumeric reads an integer or a float, which has a fraction, an
+// exponent or both, e.g. 1.5, 1e9 or 2.5e-3. A malformed exponent like the
+// one in 1e+ is left for the parser to report
+func (l *Lexer) readNumeric() token.Token {
 	pos := l.position
-	for isDigit(l.ch) {
+	ok := l.readDigits()
+	tokenType := token.TokenType(token.INT)
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
 		l.readChar()
+		ok = l.readDigits() && ok
 	}
 
-	return l.input[pos:l.position]
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		ok = l.readDigits() && ok
+	}
+
+	if !ok {
+		tokenType = token.ILLEGAL
+	}
+	return token.Token{Type: tokenType, Literal: l.input[pos:l.position]}
 }
 
+// readIdentifier reads letters and, after the first character, digits
 func (l *Lexer) readIdentifier() string {
 	pos := l.position
 
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 
@@ -51,10 +113,41 @@ func (l *Lexer) readIdentifier() string {
 func (l *Lexer) readString() string {
 	l.readChar()
 	pos := l.position
+	l.skipStringBody()
+	return l.input[pos:l.position]
+}
+
+// skipStringBody advances to the closing quote of a string. Quotes inside
+// ${...} interpolations belong to nested strings and don't end it
+func (l *Lexer) skipStringBody() {
 	for l.ch != '"' && l.ch != 0 {
+		switch {
+		case l.ch == '\\' && l.peekChar() == '$':
+			l.readChar()
+		case l.ch == '$' && l.peekChar() == '{':
+			l.readChar()
+			l.skipInterpolation()
+			if l.ch == 0 {
+				return
+			}
+		}
 		l.readChar()
 	}
-	return l.input[pos:l.position]
+}
+
+func (l *Lexer) skipInterpolation() {
+	for depth := 1; depth > 0 && l.ch != 0; {
+		l.readChar()
+		switch l.ch {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			l.readChar()
+			l.skipStringBody()
+		}
+	}
 }
 
 func (l *Lexer) chompWhitespace() {
@@ -63,7 +156,7 @@ func (l *Lexer) chompWhitespace() {
 	}
 }
 
-func newToken(t token.TokenType, literal byte) token.Token {
+func newToken(t token.TokenType, literal rune) token.Token {
 	return token.Token{Type: t, Literal: string(literal)}
 }
 
@@ -72,19 +165,37 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.chompWhitespace()
 
+	line, column := l.line, l.column
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
 			tok.Type = token.EQ
 			tok.Literal = "=="
 			l.readChar()
+		} else if l.peekChar() == '>' {
+			tok.Type = token.ARROW
+			tok.Literal = "=>"
+			l.readChar()
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '+' {
+			tok.Type = token.INCREMENT
+			tok.Literal = "++"
+			l.readChar()
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '-' && !l.operandAfterPeek() {
+			tok.Type = token.DECREMENT
+			tok.Literal = "--"
+			l.readChar()
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
@@ -118,7 +229,32 @@ func (l *Lexer) NextToken() token.Token {
 	case '/':
 		tok = newToken(token.SLASH, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			tok.Type = token.POWER
+			tok.Literal = "**"
+			l.readChar()
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
+	case '|':
+		if l.peekChar() == '>' {
+			tok.Type = token.PIPE
+			tok.Literal = "|>"
+			l.readChar()
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '.':
+		if l.peekChar() == '.' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '.' {
+			tok.Type = token.ELLIPSIS
+			tok.Literal = "..."
+			l.readChar()
+			l.readChar()
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 
 	case '"':
 		tok.Type = token.STRING
@@ -131,12 +267,13 @@ func (l *Lexer) NextToken() token.Token {
 	default:
 
 		if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok = l.readNumeric()
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -146,19 +283,35 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.readChar()
 
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
+// Tokens reads the rest of the input and returns its tokens, ending with
+// the EOF token
+func (l *Lexer) Tokens() []token.Token {
+	// Most tokens span a few bytes, so this avoids regrowing the slice for
+	// typical programs without over-allocating much
+	tokens := make([]token.Token, 0, (len(l.input)-l.position)/3+1)
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
@@ -30,6 +30,8 @@ func TestNextToken(t *testing.T) {
 	arr[3];
 	[1, 2, 3];
 	{ "a": "b" };
+	a ? b : c;
+	2 ** 3;
 `
 
 	tests := []struct {
@@ -137,6 +139,16 @@ func TestNextToken(t *testing.T) {
 		{token.STRING, "b"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "2"},
+		{token.POWER, "**"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
 	}
 
 	l := New(input)
@@ -153,3 +165,412 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+let s = "multi
+line";
+  x + s`
+
+	tests := []struct {
+		expectedToken  token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.LET, 2, 1},
+		{token.IDENT, 2, 5},
+		{token.ASSIGN, 2, 7},
+		{token.STRING, 2, 9},
+		{token.SEMICOLON, 3, 6},
+		{token.IDENT, 4, 3},
+		{token.PLUS, 4, 5},
+		{token.IDENT, 4, 7},
+		{token.EOF, 4, 8},
+	}
+
+	l := New(input)
+
+	for _, test := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != test.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", test.expectedToken, tok.Type)
+		}
+
+		if tok.Line != test.expectedLine || tok.Column != test.expectedColumn {
+			t.Fatalf("Expected %v at %v:%v but received %v:%v",
+				tok.Type, test.expectedLine, test.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestIdentifiersWithUnderscoresAndDigits(t *testing.T) {
+	l := New("let my_var_2 = x_1;")
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "my_var_2"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.IDENT, Literal: "x_1"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	for _, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Errorf("Expected %v %q, instead got %v %q", want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{"1_000_000", token.INT, "1_000_000"},
+		{"12_34", token.INT, "12_34"},
+		{"1", token.INT, "1"},
+		{"1__000", token.ILLEGAL, "1__000"},
+		{"1000_", token.ILLEGAL, "1000_"},
+		{"1_000__", token.ILLEGAL, "1_000__"},
+		{"_1000", token.IDENT, "_1000"},
+		{"_x", token.IDENT, "_x"},
+		{"log10", token.IDENT, "log10"},
+		{"x1y2", token.IDENT, "x1y2"},
+		{"x_1", token.IDENT, "x_1"},
+		{"my_var_2", token.IDENT, "my_var_2"},
+		{"a_1_b", token.IDENT, "a_1_b"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Errorf("%q: Expected token type %v but received %v", tt.input, tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%q: Expected literal %v but received %v", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%q: Expected the number to be a single token, instead got %v after it", tt.input, next.Type)
+		}
+	}
+}
+
+func TestFloats(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{"1.5", token.FLOAT, "1.5"},
+		{"1e3", token.FLOAT, "1e3"},
+		{"1E3", token.FLOAT, "1E3"},
+		{"2.5e-1", token.FLOAT, "2.5e-1"},
+		{"6.02e+23", token.FLOAT, "6.02e+23"},
+		{"1_000.000_1", token.FLOAT, "1_000.000_1"},
+		{"1e", token.FLOAT, "1e"},
+		{"1e+", token.FLOAT, "1e+"},
+		{"1.5_", token.ILLEGAL, "1.5_"},
+		{"1e3_", token.ILLEGAL, "1e3_"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Errorf("%q: Expected token type %v but received %v", tt.input, tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%q: Expected literal %v but received %v", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%q: Expected the number to be a single token, instead got %v after it", tt.input, next.Type)
+		}
+	}
+
+	// A dot is only part of a number if a digit follows it
+	l := New("1.len()")
+	for _, expected := range []token.TokenType{token.INT, token.DOT, token.IDENT} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Errorf("Expected token type %v but received %v", expected, tok.Type)
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{`"sum is ${1 + 2}"`, `sum is ${1 + 2}`},
+		{`"nested ${f("a", "b")} quotes"`, `nested ${f("a", "b")} quotes`},
+		{`"deep ${"x ${y} z"}"`, `deep ${"x ${y} z"}`},
+		{`"braces ${{"a": 1}["a"]}"`, `braces ${{"a": 1}["a"]}`},
+		{`"escaped \${"`, `escaped \${`},
+		{`"unterminated ${1 + 2"`, `unterminated ${1 + 2"`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.STRING {
+			t.Errorf("%s: Expected token type %v but received %v", tt.input, token.STRING, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%s: Expected literal %v but received %v", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%s: Expected a single string token, instead got %v after it", tt.input, next.Type)
+		}
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let größe = "café";
+größe + π`
+
+	tests := []struct {
+		expectedToken   token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "größe", 5},
+		{token.ASSIGN, "=", 11},
+		{token.STRING, "café", 13},
+		{token.SEMICOLON, ";", 19},
+		{token.IDENT, "größe", 1},
+		{token.PLUS, "+", 7},
+		{token.IDENT, "π", 9},
+		{token.EOF, "", 10},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Expected literal %v but received %v", tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Column != tt.expectedColumn {
+			t.Errorf("Expected %q at column %v but received %v", tt.expectedLiteral, tt.expectedColumn, tok.Column)
+		}
+	}
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	input := `i++; j--; a + +b - -c; 5--3; j--
+x`
+
+	tests := []struct {
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "i"},
+		{token.INCREMENT, "++"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "j"},
+		{token.DECREMENT, "--"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PLUS, "+"},
+		{token.PLUS, "+"},
+		{token.IDENT, "b"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "5"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "j"},
+		{token.DECREMENT, "--"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Expected literal %v but received %v", tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNullKeyword(t *testing.T) {
+	input := `null == nullable`
+
+	tests := []struct {
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{token.NULL, "null"},
+		{token.EQ, "=="},
+		{token.IDENT, "nullable"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Expected literal %v but received %v", tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	input := "let x = 5;\nx + 1"
+
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "5", Line: 1, Column: 9},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Type: token.IDENT, Literal: "x", Line: 2, Column: 1},
+		{Type: token.PLUS, Literal: "+", Line: 2, Column: 3},
+		{Type: token.INT, Literal: "1", Line: 2, Column: 5},
+		{Type: token.EOF, Literal: "", Line: 2, Column: 6},
+	}
+
+	tokens := New(input).Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, instead got %d: %+v", len(expected), len(tokens), tokens)
+	}
+	for i, tok := range tokens {
+		if tok != expected[i] {
+			t.Errorf("tokens[%d]: expected %+v, instead got %+v", i, expected[i], tok)
+		}
+	}
+
+	empty := New("").Tokens()
+	if len(empty) != 1 || empty[0].Type != token.EOF {
+		t.Errorf("Expected only EOF for empty input, instead got %+v", empty)
+	}
+}
+
+func TestPipe(t *testing.T) {
+	input := `x |> f | g`
+
+	tests := []struct {
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.ILLEGAL, "|"},
+		{token.IDENT, "g"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Expected literal %v but received %v", tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestDot(t *testing.T) {
+	input := `[1].len() ...rest`
+
+	tests := []struct {
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.RBRACKET, "]"},
+		{token.DOT, "."},
+		{token.IDENT, "len"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Expected literal %v but received %v", tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestArrow(t *testing.T) {
+	input := `match x { 1 => a, _ => b }`
+
+	tests := []struct {
+		expectedToken   token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.IDENT, "x"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "b"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedToken {
+			t.Fatalf("Expected token type %v but received %v", tt.expectedToken, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Expected literal %v but received %v", tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Compare orders two objects, returning a negative number if a comes before
+// b, zero if they're equal and a positive number otherwise. Numbers are
+// ordered by value, strings lexically and false before true.
+// NULL sorts after everything else. Any other combination can't be compared
+// and returns an error
+func Compare(a, b Object) (int, error) {
+	_, aNull := a.(*Null)
+	_, bNull := b.(*Null)
+	switch {
+	case aNull && bNull:
+		return 0, nil
+	case aNull:
+		return 1, nil
+	case bNull:
+		return -1, nil
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return compareInts(a.Value, b.Value), nil
+		case *BigInt:
+			return big.NewInt(a.Value).Cmp(b.Value), nil
+		case *Float:
+			return compareFloats(float64(a.Value), b.Value), nil
+		}
+
+	case *BigInt:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value.Cmp(big.NewInt(b.Value)), nil
+		case *BigInt:
+			return a.Value.Cmp(b.Value), nil
+		case *Float:
+			return compareBigFloat(a.Value, b.Value), nil
+		}
+
+	case *Float:
+		switch b := b.(type) {
+		case *Integer:
+			return compareFloats(a.Value, float64(b.Value)), nil
+		case *BigInt:
+			return -compareBigFloat(b.Value, a.Value), nil
+		case *Float:
+			return compareFloats(a.Value, b.Value), nil
+		}
+
+	case *String:
+		if b, ok := b.(*String); ok {
+			return strings.Compare(a.Value, b.Value), nil
+		}
+
+	case *Boolean:
+		if b, ok := b.(*Boolean); ok {
+			return compareInts(boolToInt(a.Value), boolToInt(b.Value)), nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot compare %v and %v", a.Type(), b.Type())
+}
+
+// Equals reports whether a and b hold the same value. Scalars are equal
+// when their types and values are, so 1 doesn't equal 1.0, and NaN doesn't
+// equal anything. Arrays are equal element by element and hashes when they
+// have the same keys with equal values, in any order. Everything else, like
+// functions and builtins, only equals itself
+func Equals(a, b Object) bool {
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && a.Value == b.Value
+
+	case *BigInt:
+		b, ok := b.(*BigInt)
+		return ok && a.Value.Cmp(b.Value) == 0
+
+	case *Float:
+		b, ok := b.(*Float)
+		return ok && a.Value == b.Value
+
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !Equals(el, b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !Equals(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareBigFloat compares like compareFloats does, so NaN is neither
+// smaller nor larger than a
+func compareBigFloat(a *big.Int, b float64) int {
+	if math.IsNaN(b) {
+		return 0
+	}
+	return new(big.Float).SetInt(a).Cmp(big.NewFloat(b))
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
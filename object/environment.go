@@ -1,18 +1,85 @@
 package object
 
+import (
+	"sort"
+	"sync"
+)
+
 func NewEnvironment() *Environment {
 	store := make(map[string]Object)
-	return &Environment{store, nil}
+	return &Environment{store: store}
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	store := make(map[string]Object)
-	return &Environment{store, outer}
+	return &Environment{store: store, outer: outer}
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store    map[string]Object
+	outer    *Environment
+	captured bool
+	// calls counts the function calls in progress in an outermost
+	// environment, see EnterCall
+	calls int
+}
+
+var environmentPool = sync.Pool{
+	New: func() interface{} {
+		return &Environment{store: make(map[string]Object)}
+	},
+}
+
+// NewPooledEnvironment is like NewEnclosedEnvironment, but reuses
+// environments handed back with Release when it can
+func NewPooledEnvironment(outer *Environment) *Environment {
+	env := environmentPool.Get().(*Environment)
+	env.outer = outer
+	return env
+}
+
+// Capture marks the environment and its outer environments as referenced
+// by something that may outlive them, such as a closure, so Release
+// leaves them alone
+func (e *Environment) Capture() {
+	for env := e; env != nil && !env.captured; env = env.outer {
+		env.captured = true
+	}
+}
+
+// Release hands the environment back for reuse by NewPooledEnvironment,
+// unless it has been captured. The environment must not be used afterwards
+func (e *Environment) Release() {
+	if e.captured {
+		return
+	}
+	for key := range e.store {
+		delete(e.store, key)
+	}
+	e.outer = nil
+	environmentPool.Put(e)
+}
+
+// EnterCall records that a function call is starting in the environment and
+// returns how many calls are now in progress. Calls are counted on the
+// outermost environment, so separate evaluations don't share a count
+func (e *Environment) EnterCall() int {
+	root := e.root()
+	root.calls++
+	return root.calls
+}
+
+// LeaveCall records that a call started with EnterCall has finished
+func (e *Environment) LeaveCall() {
+	e.root().calls--
+}
+
+func (e *Environment) root() *Environment {
+	env := e
+	for env.outer != nil {
+		env = env.outer
+	}
+	return env
 }
 
 func (e *Environment) Get(key string) (Object, bool) {
@@ -27,3 +94,71 @@ func (e *Environment) Set(key string, val Object) Object {
 	e.store[key] = val
 	return val
 }
+
+func (e *Environment) Assign(key string, val Object) (Object, bool) {
+	if _, ok := e.store[key]; ok {
+		e.store[key] = val
+		return val, true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(key, val)
+	}
+	return nil, false
+}
+
+// Delete removes key from the innermost environment that binds it and
+// reports whether there was a binding to remove
+func (e *Environment) Delete(key string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[key]; ok {
+			delete(env.store, key)
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot returns a copy of the environment that Restore can roll it back
+// to. Only the bindings are copied: the snapshot shares the outer
+// environments, whose bindings aren't saved, and the bound objects
+// themselves, so changes made inside an array or hash after the snapshot
+// survive a Restore
+func (e *Environment) Snapshot() *Environment {
+	store := make(map[string]Object, len(e.store))
+	for key, val := range e.store {
+		store[key] = val
+	}
+	return &Environment{store: store, outer: e.outer}
+}
+
+// Restore resets the environment's bindings to those of snapshot, undoing
+// any lets, assignments and unsets since it was taken. The snapshot is left
+// unchanged, so it can be restored again. Closures created in the
+// meantime keep referring to the environment and see the restored bindings
+func (e *Environment) Restore(snapshot *Environment) {
+	for key := range e.store {
+		delete(e.store, key)
+	}
+	for key, val := range snapshot.store {
+		e.store[key] = val
+	}
+	e.outer = snapshot.outer
+}
+
+// Keys returns the sorted names bound in the environment and its outer
+// environments. A name shadowed by an inner scope is only listed once
+func (e *Environment) Keys() []string {
+	seen := map[string]bool{}
+	for env := e; env != nil; env = env.outer {
+		for key := range env.store {
+			seen[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
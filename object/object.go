@@ -2,9 +2,14 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"monkey-interpreter/ast"
 )
@@ -12,18 +17,44 @@ import (
 type ObjectType string
 
 const (
-	INTEGER_OBJ      = "INTEGER"
-	BOOLEAN_OBJ      = "BOOLEAN"
-	NULL_OBJ         = "NULL"
-	RETURN_VALUE_OBJ = "RETURN_VALUE"
-	ERROR_OBJ        = "ERROR"
-	FUNCTION_OBJ     = "FUNCTION"
-	STRING_OBJ       = "STRING"
-	BUILTIN_OBJ      = "BUILTIN"
-	ARRAY_OBJ        = "ARRAY"
-	HASH_OBJ         = "HASH"
+	INTEGER_OBJ      ObjectType = "INTEGER"
+	FLOAT_OBJ        ObjectType = "FLOAT"
+	BOOLEAN_OBJ      ObjectType = "BOOLEAN"
+	NULL_OBJ         ObjectType = "NULL"
+	RETURN_VALUE_OBJ ObjectType = "RETURN_VALUE"
+	ERROR_OBJ        ObjectType = "ERROR"
+	FUNCTION_OBJ     ObjectType = "FUNCTION"
+	STRING_OBJ       ObjectType = "STRING"
+	BUILTIN_OBJ      ObjectType = "BUILTIN"
+	ARRAY_OBJ        ObjectType = "ARRAY"
+	HASH_OBJ         ObjectType = "HASH"
+	BREAK_OBJ        ObjectType = "BREAK"
+	CONTINUE_OBJ     ObjectType = "CONTINUE"
+	QUOTE_OBJ        ObjectType = "QUOTE"
+	MACRO_OBJ        ObjectType = "MACRO"
 )
 
+// ObjectTypes lists every type an Object can have
+var ObjectTypes = []ObjectType{
+	INTEGER_OBJ,
+	FLOAT_OBJ,
+	BOOLEAN_OBJ,
+	NULL_OBJ,
+	RETURN_VALUE_OBJ,
+	ERROR_OBJ,
+	FUNCTION_OBJ,
+	STRING_OBJ,
+	BUILTIN_OBJ,
+	ARRAY_OBJ,
+	HASH_OBJ,
+	BREAK_OBJ,
+	CONTINUE_OBJ,
+	QUOTE_OBJ,
+	MACRO_OBJ,
+}
+
+func (t ObjectType) String() string { return string(t) }
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
@@ -45,6 +76,73 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: INTEGER_OBJ, Value: uint64(i.Value)}
 }
 
+// BigInt is an integer too large for an Integer, which only comes up when
+// the evaluator is set to switch to arbitrary precision on overflow. It
+// reports itself as an INTEGER, and values that fit in an Integer should
+// always be one, so an Integer and a BigInt are never equal
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType {
+	return INTEGER_OBJ
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+// bigIntKey keeps the hash keys of big integers apart from those of
+// integers, which use the value itself
+const bigIntKey ObjectType = "BIG_INTEGER"
+
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	if b.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	h.Write(b.Value.Bytes())
+	return HashKey{Type: bigIntKey, Value: h.Sum64()}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// Inspect always includes a decimal point or an exponent, so 1.0 doesn't
+// print like the integer 1
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// nanKeys counts the NaN hash keys handed out so far
+var nanKeys uint64
+
+// HashKey uses the bits of the value, with -0 folded into 0. NaN isn't
+// equal to anything, itself included, so every NaN gets a fresh key: it can
+// be stored in a hash but never looked up again
+func (f *Float) HashKey() HashKey {
+	switch {
+	case math.IsNaN(f.Value):
+		// Any bit pattern with all exponent bits and some mantissa bits set is
+		// a NaN, so these keys can't collide with those of other floats
+		const exponent, mantissa = 0x7FF0000000000000, 0x000FFFFFFFFFFFFF
+		n := atomic.AddUint64(&nanKeys, 1)
+		return HashKey{Type: FLOAT_OBJ, Value: exponent | (n%mantissa + 1)}
+	case f.Value == 0:
+		return HashKey{Type: FLOAT_OBJ, Value: 0}
+	}
+	return HashKey{Type: FLOAT_OBJ, Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -77,15 +175,50 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 type Error struct {
 	Message string
+	Line    int
+	Column  int
+	// Stack lists the function calls in progress when the error was
+	// created, innermost first. MoreFrames counts the outer calls left out
+	// of it to keep it short
+	Stack      []string
+	MoreFrames int
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	var buf bytes.Buffer
+	buf.WriteString("ERROR: ")
+	if e.Line != 0 {
+		fmt.Fprintf(&buf, "%d:%d: ", e.Line, e.Column)
+	}
+	buf.WriteString(e.Message)
+	for _, frame := range e.Stack {
+		buf.WriteString("\n    at " + frame)
+	}
+	if e.MoreFrames > 0 {
+		fmt.Fprintf(&buf, "\n    ... %d more", e.MoreFrames)
+	}
+	return buf.String()
+}
 
+// Function is a closure. Name is empty for anonymous functions
 type Function struct {
+	Name       string
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -102,8 +235,14 @@ func (f *Function) Inspect() string {
 	for _, p := range f.Parameters {
 		params = append(params, p.Value)
 	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.Value)
+	}
 
 	buf.WriteString("fn")
+	if f.Name != "" {
+		buf.WriteString(" " + f.Name)
+	}
 	buf.WriteString("(")
 	buf.WriteString(strings.Join(params, ", "))
 	buf.WriteString(")")
@@ -130,9 +269,14 @@ func (s *String) HashKey() HashKey {
 }
 
 type (
-	BuiltinFn func(args ...Object) Object
-	Builtin   struct {
-		Fn BuiltinFn
+	BuiltinFn    func(args ...Object) Object
+	EnvBuiltinFn func(env *Environment, args ...Object) Object
+
+	// Builtin is a function implemented in Go. Builtins that need the
+	// environment they're called from, like eval, set EnvFn instead of Fn
+	Builtin struct {
+		Fn    BuiltinFn
+		EnvFn EnvBuiltinFn
 	}
 )
 
@@ -144,11 +288,21 @@ type Array struct {
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
-func (a *Array) Inspect() string {
+func (a *Array) Inspect() string  { return a.inspect(map[Object]bool{}) }
+
+// inspect prints an array that contains itself, which index assignment can
+// create, as [...] where it recurs. seen holds the containers being printed
+func (a *Array) inspect(seen map[Object]bool) string {
+	if seen[a] {
+		return "[...]"
+	}
+	seen[a] = true
+	defer delete(seen, a)
+
 	var out bytes.Buffer
 	elements := []string{}
 	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspectNested(e, seen))
 	}
 	out.WriteString("[")
 	out.WriteString(strings.Join(elements, ", "))
@@ -156,6 +310,27 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, e := range a.Elements {
+		key := e.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf, key.Value)
+		h.Write(buf)
+	}
+	return HashKey{Type: ARRAY_OBJ, Value: h.Sum64()}
+}
+
+func (a *Array) IsHashable() bool {
+	for _, e := range a.Elements {
+		if _, ok := AsHashable(e); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -165,18 +340,65 @@ type HashPair struct {
 	Key   Object
 	Value Object
 }
+
+// Hash keeps its pairs in insertion order. Pairs should only be changed
+// through Set and Delete so that Keys stays in sync
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set adds or replaces a pair. A replaced key keeps its original position
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
+}
+
+func (h *Hash) Delete(key HashKey) {
+	if _, ok := h.Pairs[key]; !ok {
+		return
+	}
+	delete(h.Pairs, key)
+	for i, k := range h.Keys {
+		if k == key {
+			h.Keys = append(h.Keys[:i:i], h.Keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// OrderedPairs returns the pairs in insertion order
+func (h *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		pairs = append(pairs, h.Pairs[key])
+	}
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
-func (h *Hash) Inspect() string {
+func (h *Hash) Inspect() string  { return h.inspect(map[Object]bool{}) }
+
+// inspect is Array.inspect for hashes, printing them as {...} where they recur
+func (h *Hash) inspect(seen map[Object]bool) string {
+	if seen[h] {
+		return "{...}"
+	}
+	seen[h] = true
+	defer delete(seen, h)
+
 	buf := bytes.Buffer{}
 
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, strings.Join([]string{pair.Key.Inspect(), pair.Value.Inspect()}, " : "))
+	for _, pair := range h.OrderedPairs() {
+		pairs = append(pairs, strings.Join([]string{inspectNested(pair.Key, seen), inspectNested(pair.Value, seen)}, " : "))
 	}
 
 	buf.WriteString("{\n")
@@ -186,6 +408,71 @@ func (h *Hash) Inspect() string {
 	return buf.String()
 }
 
+// inspectNested inspects an element of an array or hash, keeping track of
+// the containers already being printed
+func inspectNested(obj Object, seen map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(seen)
+	case *Hash:
+		return obj.inspect(seen)
+	}
+	return obj.Inspect()
+}
+
 type Hashable interface {
 	HashKey() HashKey
 }
+
+// CompositeHashable is implemented by containers that can only be used as
+// hash keys when all of their elements are hashable
+type CompositeHashable interface {
+	Hashable
+	IsHashable() bool
+}
+
+func AsHashable(obj Object) (Hashable, bool) {
+	h, ok := obj.(Hashable)
+	if !ok {
+		return nil, false
+	}
+	if c, ok := h.(CompositeHashable); ok && !c.IsHashable() {
+		return nil, false
+	}
+	return h, true
+}
+
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	buf := bytes.Buffer{}
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.Value)
+	}
+
+	buf.WriteString("macro")
+	buf.WriteString("(")
+	buf.WriteString(strings.Join(params, ", "))
+	buf.WriteString(")")
+	buf.WriteString("{\n")
+	buf.WriteString(m.Body.String())
+	buf.WriteString("\n}")
+
+	return buf.String()
+}
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +21,429 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	three1 := &Float{Value: 3.0}
+	three2 := &Float{Value: 3.0}
+	other := &Float{Value: 3.5}
+	if three1.HashKey() != three2.HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+	if three1.HashKey() == other.HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+	if three1.HashKey() == (&Integer{Value: 3}).HashKey() {
+		t.Errorf("float has same hash key as the integer with the same value")
+	}
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("0 and -0 have different hash keys")
+	}
+
+	nan := &Float{Value: math.NaN()}
+	if nan.HashKey() == nan.HashKey() {
+		t.Errorf("NaN has the same hash key twice")
+	}
+	if nan.HashKey() == (&Float{Value: math.Inf(1)}).HashKey() {
+		t.Errorf("NaN has the same hash key as infinity")
+	}
+
+	hash := NewHash()
+	hash.Set(three1.HashKey(), HashPair{Key: three1, Value: &String{Value: "three"}})
+	pair, ok := hash.Pairs[three2.HashKey()]
+	if !ok || pair.Value.(*String).Value != "three" {
+		t.Errorf("could not retrieve a float-keyed hash entry, got %v", pair.Value)
+	}
+	if _, ok := hash.Pairs[other.HashKey()]; ok {
+		t.Errorf("found an entry under a different float key")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1, "1.0"},
+		{-2, "-2.0"},
+		{0, "0.0"},
+		{1.5, "1.5"},
+		{1000, "1000.0"},
+		{1e21, "1e+21"},
+		{2.5e-7, "2.5e-07"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("Inspect of %v wrong. got=%q, want=%q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	big1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}
+	big2 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}
+	negative := &BigInt{Value: new(big.Int).Neg(big1.Value)}
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+	if big1.HashKey() == negative.HashKey() {
+		t.Errorf("big integers with opposite signs have same hash keys")
+	}
+	if (&BigInt{Value: big.NewInt(0)}).HashKey() == (&Integer{Value: 0}).HashKey() {
+		t.Errorf("big integer has same hash key as an integer")
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	one := &Integer{Value: 1}
+	two := &Integer{Value: 2}
+	arr1 := &Array{Elements: []Object{one, two}}
+	arr2 := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+	reversed := &Array{Elements: []Object{two, one}}
+	nested := &Array{Elements: []Object{arr1}}
+	strs := &Array{Elements: []Object{&String{Value: "1"}, &String{Value: "2"}}}
+
+	if arr1.HashKey() != arr2.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+	if arr1.HashKey() == reversed.HashKey() {
+		t.Errorf("arrays with different order have same hash keys")
+	}
+	if arr1.HashKey() == nested.HashKey() {
+		t.Errorf("nested array has same hash key as its element")
+	}
+	if arr1.HashKey() == strs.HashKey() {
+		t.Errorf("arrays with elements of different types have same hash keys")
+	}
+
+	if _, ok := AsHashable(arr1); !ok {
+		t.Errorf("array of integers is not hashable")
+	}
+	unhashable := &Array{Elements: []Object{one, &Function{}}}
+	if _, ok := AsHashable(unhashable); ok {
+		t.Errorf("array containing a function is hashable")
+	}
+	if _, ok := AsHashable(&Array{Elements: []Object{unhashable}}); ok {
+		t.Errorf("array containing an unhashable array is hashable")
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	a := &String{Value: "a"}
+	b := &String{Value: "b"}
+	c := &String{Value: "c"}
+
+	hash := NewHash()
+	hash.Set(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 1}})
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+	hash.Set(c.HashKey(), HashPair{Key: c, Value: &Integer{Value: 3}})
+	hash.Set(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 4}})
+
+	expected := "{\n\"b\" : 4,\n\"a\" : 2,\n\"c\" : 3\n}"
+	if hash.Inspect() != expected {
+		t.Errorf("hash.Inspect() wrong. want=%q, got=%q", expected, hash.Inspect())
+	}
+
+	hash.Delete(a.HashKey())
+	hash.Delete(a.HashKey())
+
+	expected = "{\n\"b\" : 4,\n\"c\" : 3\n}"
+	if hash.Inspect() != expected {
+		t.Errorf("hash.Inspect() wrong. want=%q, got=%q", expected, hash.Inspect())
+	}
+	if len(hash.Keys) != len(hash.Pairs) {
+		t.Errorf("Keys and Pairs out of sync. got keys=%d, pairs=%d", len(hash.Keys), len(hash.Pairs))
+	}
+}
+
+func TestEnvironmentKeys(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", &Integer{Value: 1})
+	outer.Set("a", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("c", &Integer{Value: 3})
+	inner.Set("a", &Integer{Value: 4})
+
+	tests := []struct {
+		env      *Environment
+		expected []string
+	}{
+		{NewEnvironment(), []string{}},
+		{outer, []string{"a", "b"}},
+		{inner, []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		keys := tt.env.Keys()
+		if len(keys) != len(tt.expected) {
+			t.Errorf("Keys() wrong. want=%v, got=%v", tt.expected, keys)
+			continue
+		}
+		for i, key := range tt.expected {
+			if keys[i] != key {
+				t.Errorf("Keys() wrong. want=%v, got=%v", tt.expected, keys)
+				break
+			}
+		}
+	}
+}
+
+func TestEnvironmentDelete(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	outer.Set("b", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("a", &Integer{Value: 3})
+
+	if !inner.Delete("a") {
+		t.Fatalf("Expected Delete to remove a")
+	}
+	val, ok := inner.Get("a")
+	if !ok || val.(*Integer).Value != 1 {
+		t.Errorf("Expected the outer a to be visible after deleting the inner one, got %v", val)
+	}
+
+	if !inner.Delete("b") {
+		t.Fatalf("Expected Delete to remove b from the outer environment")
+	}
+	if _, ok := outer.Get("b"); ok {
+		t.Errorf("Expected b to be removed from the outer environment")
+	}
+
+	if inner.Delete("missing") {
+		t.Errorf("Expected Delete of a missing name to return false")
+	}
+}
+
+func TestEnvironmentSnapshot(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+
+	env := NewEnclosedEnvironment(outer)
+	array := &Array{Elements: []Object{&Integer{Value: 1}}}
+	env.Set("b", &Integer{Value: 2})
+	env.Set("arr", array)
+
+	snapshot := env.Snapshot()
+	env.Set("c", &Integer{Value: 3})
+	env.Set("b", &Integer{Value: 4})
+	env.Delete("arr")
+	array.Elements = append(array.Elements, &Integer{Value: 2})
+
+	env.Restore(snapshot)
+	if _, ok := env.Get("c"); ok {
+		t.Errorf("Expected c to be removed by Restore")
+	}
+	if val, ok := env.Get("b"); !ok || val.(*Integer).Value != 2 {
+		t.Errorf("Expected b to be restored to 2, got %v", val)
+	}
+	if val, ok := env.Get("arr"); !ok || len(val.(*Array).Elements) != 2 {
+		t.Errorf("Expected arr to be restored and share its elements, got %v", val)
+	}
+	if val, ok := env.Get("a"); !ok || val.(*Integer).Value != 1 {
+		t.Errorf("Expected the outer environment to stay visible, got %v", val)
+	}
+
+	// The snapshot can be restored more than once
+	env.Set("d", &Integer{Value: 5})
+	env.Restore(snapshot)
+	if keys := env.Keys(); len(keys) != 3 || keys[0] != "a" || keys[1] != "arr" || keys[2] != "b" {
+		t.Errorf("Expected keys [a arr b] after restoring again, got %v", keys)
+	}
+}
+
+func TestPooledEnvironment(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+
+	env := NewPooledEnvironment(outer)
+	if len(env.Keys()) != 1 {
+		t.Fatalf("Expected a pooled environment to start empty, got %v", env.Keys())
+	}
+	env.Set("b", &Integer{Value: 2})
+	env.Release()
+
+	for i := 0; i < 10; i++ {
+		env := NewPooledEnvironment(outer)
+		if keys := env.Keys(); len(keys) != 1 || keys[0] != "a" {
+			t.Fatalf("Expected a reused environment to be cleared, got %v", keys)
+		}
+		env.Release()
+	}
+
+	captured := NewPooledEnvironment(outer)
+	inner := NewEnclosedEnvironment(captured)
+	captured.Set("c", &Integer{Value: 3})
+	inner.Capture()
+	captured.Release()
+
+	if val, ok := inner.Get("c"); !ok || val.(*Integer).Value != 3 {
+		t.Errorf("Expected a captured environment to survive Release, got %v", val)
+	}
+	if val, ok := inner.Get("a"); !ok || val.(*Integer).Value != 1 {
+		t.Errorf("Expected a captured environment to keep its outer environment, got %v", val)
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	array.Elements[1] = array
+	if array.Inspect() != "[1, [...]]" {
+		t.Errorf("array.Inspect() wrong. got=%q", array.Inspect())
+	}
+
+	hash := NewHash()
+	key := &String{Value: "self"}
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: hash})
+	if hash.Inspect() != "{\n\"self\" : {...}\n}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+}
+
+func TestCompare(t *testing.T) {
+	null := &Null{}
+
+	tests := []struct {
+		a, b     Object
+		expected int
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 2}, -1},
+		{&Integer{Value: 2}, &Integer{Value: 2}, 0},
+		{&Integer{Value: 3}, &Integer{Value: -2}, 1},
+		{&Float{Value: 1.5}, &Integer{Value: 2}, -1},
+		{&Integer{Value: 2}, &Float{Value: 1.5}, 1},
+		{&Float{Value: 2}, &Integer{Value: 2}, 0},
+		{&BigInt{Value: big.NewInt(5)}, &Integer{Value: 7}, -1},
+		{&Integer{Value: 7}, &BigInt{Value: big.NewInt(5)}, 1},
+		{&BigInt{Value: big.NewInt(-5)}, &BigInt{Value: big.NewInt(-5)}, 0},
+		{&BigInt{Value: big.NewInt(3)}, &Float{Value: 2.5}, 1},
+		{&Float{Value: 2.5}, &BigInt{Value: big.NewInt(3)}, -1},
+		{&Float{Value: math.NaN()}, &BigInt{Value: big.NewInt(3)}, 0},
+		{&String{Value: "apple"}, &String{Value: "banana"}, -1},
+		{&String{Value: "b"}, &String{Value: "B"}, 1},
+		{&String{Value: ""}, &String{Value: ""}, 0},
+		{&Boolean{Value: false}, &Boolean{Value: true}, -1},
+		{&Boolean{Value: true}, &Boolean{Value: false}, 1},
+		{&Boolean{Value: true}, &Boolean{Value: true}, 0},
+		{null, &Integer{Value: 1}, 1},
+		{&String{Value: "a"}, null, -1},
+		{null, &Boolean{Value: false}, 1},
+		{null, null, 0},
+	}
+
+	for _, tt := range tests {
+		cmp, err := Compare(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("Compare(%v, %v) returned an error: %v", tt.a.Inspect(), tt.b.Inspect(), err)
+			continue
+		}
+		if sign(cmp) != tt.expected {
+			t.Errorf("Compare(%v, %v) wrong. want=%d, got=%d", tt.a.Inspect(), tt.b.Inspect(), tt.expected, cmp)
+		}
+	}
+
+	errTests := []struct {
+		a, b     Object
+		expected string
+	}{
+		{&Integer{Value: 1}, &String{Value: "1"}, "cannot compare INTEGER and STRING"},
+		{&Boolean{Value: true}, &Integer{Value: 1}, "cannot compare BOOLEAN and INTEGER"},
+		{&Array{}, &Array{}, "cannot compare ARRAY and ARRAY"},
+	}
+
+	for _, tt := range errTests {
+		_, err := Compare(tt.a, tt.b)
+		if err == nil {
+			t.Errorf("Expected Compare(%v, %v) to return an error", tt.a.Inspect(), tt.b.Inspect())
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestEquals(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := NewHash()
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i].(Hashable).HashKey(), HashPair{Key: pairs[i], Value: pairs[i+1]})
+		}
+		return h
+	}
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+	fn := &Function{}
+	builtin := &Builtin{}
+	nan := &Float{Value: math.NaN()}
+	err := &Error{Message: "boom"}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{one, &Integer{Value: 1}, true},
+		{one, two, false},
+		{&Float{Value: 1.5}, &Float{Value: 1.5}, true},
+		{&Float{Value: 0}, &Float{Value: math.Copysign(0, -1)}, true},
+		{nan, &Float{Value: math.NaN()}, false},
+		{&Float{Value: 1}, one, false},
+		{one, &Float{Value: 1}, false},
+		{a, &String{Value: "a"}, true},
+		{a, b, false},
+		{&String{Value: "1"}, one, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: true}, &Boolean{Value: false}, false},
+		{&Boolean{Value: false}, &Null{}, false},
+		{&Null{}, &Null{}, true},
+		{&Null{}, &Integer{Value: 0}, false},
+		{&Array{Elements: []Object{}}, &Array{Elements: []Object{}}, true},
+		{&Array{Elements: []Object{one, a}}, &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, true},
+		{&Array{Elements: []Object{one, a}}, &Array{Elements: []Object{a, one}}, false},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{one, one}}, false},
+		{&Array{Elements: []Object{&Array{Elements: []Object{two}}}}, &Array{Elements: []Object{&Array{Elements: []Object{two}}}}, true},
+		{&Array{Elements: []Object{fn}}, &Array{Elements: []Object{fn}}, true},
+		{&Array{Elements: []Object{fn}}, &Array{Elements: []Object{&Function{}}}, false},
+		{&Array{Elements: []Object{one}}, hash(one, one), false},
+		{hash(), hash(), true},
+		{hash(a, one, b, two), hash(b, two, a, one), true},
+		{hash(a, one), hash(a, two), false},
+		{hash(a, one), hash(b, one), false},
+		{hash(a, one), hash(a, one, b, two), false},
+		{hash(a, &Array{Elements: []Object{one}}), hash(a, &Array{Elements: []Object{one}}), true},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+		{builtin, builtin, true},
+		{builtin, &Builtin{}, false},
+		{fn, builtin, false},
+		{err, err, true},
+		{err, &Error{Message: "boom"}, false},
+		{&BigInt{Value: big.NewInt(1)}, &BigInt{Value: big.NewInt(1)}, true},
+		{&BigInt{Value: big.NewInt(1)}, &BigInt{Value: big.NewInt(2)}, false},
+		{&BigInt{Value: big.NewInt(1)}, one, false},
+	}
+
+	for _, tt := range tests {
+		if Equals(tt.a, tt.b) != tt.expected {
+			t.Errorf("Equals(%v, %v) wrong. want=%t", tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+		}
+		if Equals(tt.b, tt.a) != tt.expected {
+			t.Errorf("Equals(%v, %v) wrong. want=%t", tt.b.Inspect(), tt.a.Inspect(), tt.expected)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"monkey-interpreter/ast"
 	"monkey-interpreter/lexer"
@@ -12,10 +14,14 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // =
+	TERNARY     // a ? b : c
 	EQUALS      // ==
 	LESSGREATER // > or <
+	PIPE        // x |> f
 	SUM         // +
 	PRODUCT     // *
+	POWER       // **
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
@@ -33,6 +39,11 @@ type Parser struct {
 	errors         []string
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+	blockDepth     int
+
+	// BigIntegers makes integer literals that don't fit in 64 bits parse as
+	// BigIntegerLiterals instead of being errors
+	BigIntegers bool
 }
 
 func (p *Parser) registerPrefixFn(t token.TokenType, fn prefixParseFn) {
@@ -48,13 +59,19 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = map[token.TokenType]prefixParseFn{}
 	p.registerPrefixFn(token.IDENT, p.parseIdentifier)
 	p.registerPrefixFn(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixFn(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefixFn(token.BANG, p.parsePrefixExpression)
 	p.registerPrefixFn(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefixFn(token.PLUS, p.parsePrefixExpression)
 	p.registerPrefixFn(token.TRUE, p.parseBoolean)
 	p.registerPrefixFn(token.FALSE, p.parseBoolean)
+	p.registerPrefixFn(token.NULL, p.parseNull)
 	p.registerPrefixFn(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefixFn(token.IF, p.parseIfExpression)
+	p.registerPrefixFn(token.DO, p.parseDoExpression)
+	p.registerPrefixFn(token.MATCH, p.parseMatchExpression)
 	p.registerPrefixFn(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefixFn(token.MACRO, p.parseMacroLiteral)
 	p.registerPrefixFn(token.STRING, p.parseStringLiteral)
 	p.registerPrefixFn(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefixFn(token.LBRACE, p.parseHashLiteral)
@@ -64,12 +81,19 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixFn(token.MINUS, p.parseInfixExpression)
 	p.registerInfixFn(token.SLASH, p.parseInfixExpression)
 	p.registerInfixFn(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfixFn(token.POWER, p.parseInfixExpression)
 	p.registerInfixFn(token.EQ, p.parseInfixExpression)
 	p.registerInfixFn(token.NOT_EQ, p.parseInfixExpression)
-	p.registerInfixFn(token.LT, p.parseInfixExpression)
-	p.registerInfixFn(token.GT, p.parseInfixExpression)
+	p.registerInfixFn(token.LT, p.parseComparison)
+	p.registerInfixFn(token.GT, p.parseComparison)
 	p.registerInfixFn(token.LPAREN, p.parseCallExpression)
+	p.registerInfixFn(token.PIPE, p.parsePipeExpression)
 	p.registerInfixFn(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixFn(token.DOT, p.parseMethodCall)
+	p.registerInfixFn(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfixFn(token.QUESTION, p.parseTernaryExpression)
+	p.registerInfixFn(token.INCREMENT, p.parsePostfixExpression)
+	p.registerInfixFn(token.DECREMENT, p.parsePostfixExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -85,11 +109,43 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// Exponentiation is right-associative
+		precedence--
+	}
 	p.nextToken()
 	expr.Right = p.parseExpression(precedence)
 	return expr
 }
 
+// parseComparison parses a relational expression. Chained operators like
+// a < b < c are collected into a single ComparisonChain, while a lone
+// comparison stays an InfixExpression
+func (p *Parser) parseComparison(left ast.Expression) ast.Expression {
+	chain := &ast.ComparisonChain{Token: p.curToken, Operands: []ast.Expression{left}}
+
+	for {
+		chain.Operators = append(chain.Operators, p.curToken.Literal)
+		p.nextToken()
+		chain.Operands = append(chain.Operands, p.parseExpression(LESSGREATER))
+
+		if !p.peekTokenIs(token.LT) && !p.peekTokenIs(token.GT) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if len(chain.Operators) == 1 {
+		return &ast.InfixExpression{
+			Token:    chain.Token,
+			Left:     left,
+			Operator: chain.Operators[0],
+			Right:    chain.Operands[1],
+		}
+	}
+	return chain
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	// defer untrace(trace("parsePrefixExpression"))
 	expr := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
@@ -100,20 +156,157 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	if !strings.Contains(p.curToken.Literal, "${") {
+		return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	return p.parseInterpolatedString()
+}
+
+// parseInterpolatedString splits a string into literal segments and the
+// expressions inside ${...}, which are parsed by a separate parser. \${
+// produces a literal ${
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	literal := p.curToken.Literal
+
+	var segment strings.Builder
+	addSegment := func() {
+		if segment.Len() > 0 {
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: segment.String()})
+			segment.Reset()
+		}
+	}
+
+	for i := 0; i < len(literal); i++ {
+		if strings.HasPrefix(literal[i:], "\\${") {
+			segment.WriteString("${")
+			i += 2
+			continue
+		}
+		if !strings.HasPrefix(literal[i:], "${") {
+			segment.WriteByte(literal[i])
+			continue
+		}
+
+		end := interpolationEnd(literal, i+2)
+		if end < 0 {
+			p.errors = append(p.errors, fmt.Sprintf("%d:%d: Unterminated ${ in string",
+				p.curToken.Line, p.curToken.Column))
+			return nil
+		}
+
+		expr := p.parseInterpolation(literal[i+2 : end])
+		if expr == nil {
+			return nil
+		}
+		addSegment()
+		str.Parts = append(str.Parts, expr)
+		i = end
+	}
+	addSegment()
+
+	return str
+}
+
+func (p *Parser) parseInterpolation(source string) ast.Expression {
+	sub := New(lexer.New(source))
+	program := sub.ParseProgram()
+	for _, msg := range sub.Errors() {
+		p.errors = append(p.errors, fmt.Sprintf("%d:%d: In string interpolation: %v",
+			p.curToken.Line, p.curToken.Column, msg))
+	}
+	if len(sub.Errors()) > 0 {
+		return nil
+	}
+
+	if len(program.Statements) != 1 {
+		p.errors = append(p.errors, fmt.Sprintf("%d:%d: String interpolation must contain a single expression",
+			p.curToken.Line, p.curToken.Column))
+		return nil
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		p.errors = append(p.errors, fmt.Sprintf("%d:%d: String interpolation must contain a single expression",
+			p.curToken.Line, p.curToken.Column))
+		return nil
+	}
+	return stmt.Expression
+}
+
+// interpolationEnd returns the index of the } closing an interpolation whose
+// contents start at i, or -1 if there is none. It follows the same rules as
+// the lexer, so braces inside nested strings are skipped
+func interpolationEnd(s string, i int) int {
+	for depth := 1; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"':
+			if i = stringEnd(s, i+1); i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+func stringEnd(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			return i
+		case strings.HasPrefix(s[i:], "\\$"):
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			if i = interpolationEnd(s, i+2); i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
 }
 
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.BooleanExpression{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	// defer untrace(trace("parseIntegerLiteral"))
 	lit := &ast.IntegerLiteral{Token: p.curToken}
-	val, err := strconv.ParseInt(p.curToken.Literal, 10, 32)
+	digits := strings.ReplaceAll(p.curToken.Literal, "_", "")
+	val, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil && p.BigIntegers {
+		if val, ok := new(big.Int).SetString(digits, 10); ok {
+			return &ast.BigIntegerLiteral{Token: p.curToken, Value: val}
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("%d:%d: could not parse %v as integer",
+			p.curToken.Line, p.curToken.Column, p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	lit.Value = val
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	val, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %v as integer", p.curToken.Literal)
+		msg := fmt.Sprintf("%d:%d: could not parse %v as float",
+			p.curToken.Line, p.curToken.Column, p.curToken.Literal)
 		p.errors = append(p.errors, msg)
+		return nil
 	}
 	lit.Value = val
 	return lit
@@ -135,31 +328,125 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
-	for p.curToken.Type != token.EOF {
-		statement := p.parseStatement()
+	for {
+		statement, ok := p.NextStatement()
+		if !ok {
+			return program
+		}
 		program.Statements = append(program.Statements, statement)
+	}
+}
+
+// NextStatement parses a single statement, so it can be processed before
+// the rest of the input is read. ok is false once the input is exhausted.
+// Statements with syntax errors are skipped and reported through Errors
+func (p *Parser) NextStatement() (statement ast.Statement, ok bool) {
+	for p.curToken.Type != token.EOF {
+		statement = p.parseStatement()
 		p.nextToken()
+		if statement != nil {
+			return statement, true
+		}
 	}
-	return program
+	return nil, false
 }
 
+// parseStatement returns nil for incomplete statements. After a syntax error
+// it skips ahead to the next statement boundary so parsing can continue
 func (p *Parser) parseStatement() ast.Statement {
+	errorCount := len(p.errors)
+
+	var statement ast.Statement
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+			if stmt := p.parseDestructuringStatement(); stmt != nil && stmt.Value != nil {
+				statement = stmt
+			}
+		} else if stmt := p.parseLetStatement(); stmt != nil && stmt.Value != nil {
+			statement = stmt
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			statement = stmt
+		}
+	case token.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			statement = stmt
+		}
+	case token.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			statement = stmt
+		}
+	case token.BREAK:
+		statement = &ast.BreakStatement{Token: p.curToken}
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	case token.CONTINUE:
+		statement = &ast.ContinueStatement{Token: p.curToken}
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
 	default:
-		return p.parseExpressionStatement()
+		if stmt := p.parseExpressionStatement(); stmt.Expression != nil {
+			statement = stmt
+		}
+	}
+
+	if len(p.errors) > errorCount {
+		p.synchronize()
+	}
+
+	return statement
+}
+
+// synchronize skips the rest of a statement that failed to parse. Braces
+// opened while skipping are consumed through their closing brace, and a
+// closing brace that ends the enclosing block is left for the block to see.
+func (p *Parser) synchronize() {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curTokenIs(token.LBRACE):
+			depth++
+		case p.curTokenIs(token.RBRACE) && depth > 0:
+			depth--
+		case p.curTokenIs(token.SEMICOLON) && depth == 0:
+			return
+		}
+		if depth == 0 {
+			if p.peekTokenIs(token.LET) || p.peekTokenIs(token.RETURN) {
+				return
+			}
+			if p.peekTokenIs(token.RBRACE) && p.blockDepth > 0 {
+				return
+			}
+		}
+		p.nextToken()
 	}
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	statement := &ast.ReturnStatement{Token: p.curToken}
-	p.nextToken()
 
+	// A bare return leaves ReturnValue nil
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return statement
+	}
+
+	p.nextToken()
 	statement.ReturnValue = p.parseExpression(LOWEST)
-	p.expectPeek(token.SEMICOLON)
+	if statement.ReturnValue == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
 
 	return statement
 }
@@ -181,27 +468,158 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	statement.Value = p.parseExpression(LOWEST)
 
-	p.expectPeek(token.SEMICOLON)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseDestructuringStatement() *ast.DestructuringStatement {
+	statement := &ast.DestructuringStatement{Token: p.curToken}
+	p.nextToken()
+
+	end := token.TokenType(token.RBRACKET)
+	if p.curTokenIs(token.LBRACE) {
+		statement.Hash = true
+		end = token.RBRACE
+	}
+
+	for !p.peekTokenIs(end) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		statement.Names = append(statement.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(end) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	statement := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	if !p.curTokenIs(token.SEMICOLON) {
+		if p.curTokenIs(token.LET) {
+			init := p.parseLetStatement()
+			if init == nil {
+				return nil
+			}
+			statement.Init = init
+		} else {
+			statement.Init = p.parseExpressionStatement()
+		}
+
+		if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	if !p.curTokenIs(token.SEMICOLON) {
+		statement.Condition = p.parseExpression(LOWEST)
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	if !p.curTokenIs(token.RPAREN) {
+		statement.Post = &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	statement := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	statement.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
 
 	return statement
 }
 
 func (p *Parser) noPrefixParseFuncError(t token.TokenType) {
-	msg := fmt.Sprintf("No prefix parse function found for %v", t)
+	msg := fmt.Sprintf("%d:%d: No prefix parse function found for %v",
+		p.curToken.Line, p.curToken.Column, t)
+	if t == token.ILLEGAL {
+		msg = fmt.Sprintf("%d:%d: Illegal token %v",
+			p.curToken.Line, p.curToken.Column, p.curToken.Literal)
+	}
 	p.errors = append(p.errors, msg)
 }
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:    ASSIGN,
+	token.QUESTION:  TERNARY,
+	token.PIPE:      PIPE,
+	token.EQ:        EQUALS,
+	token.NOT_EQ:    EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.POWER:     POWER,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
+	token.INCREMENT: INDEX,
+	token.DECREMENT: INDEX,
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
@@ -214,7 +632,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
-	expr := &ast.IfExpression{}
+	expr := &ast.IfExpression{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil
@@ -237,6 +655,19 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		// `else if` is sugar for an else block holding a single nested if
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			block := &ast.BlockStatement{Token: p.curToken}
+			nested := p.parseIfExpression()
+			if nested == nil {
+				return nil
+			}
+			block.Statements = []ast.Statement{&ast.ExpressionStatement{Token: block.Token, Expression: nested}}
+			expr.Alternative = block
+			return expr
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -247,76 +678,268 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expr
 }
 
+func (p *Parser) parseDoExpression() ast.Expression {
+	expr := &ast.DoExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expr.Body = p.parseBlockStatement()
+	return expr
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expr := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	expr.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parseExpression(LOWEST)}
+		if arm.Pattern == nil {
+			return nil
+		}
+		if !isMatchPattern(arm.Pattern) {
+			p.errors = append(p.errors, fmt.Sprintf("%d:%d: Invalid match pattern %v",
+				p.curToken.Line, p.curToken.Column, arm.Pattern))
+			return nil
+		}
+		if len(expr.Arms) > 0 && expr.Arms[len(expr.Arms)-1].IsWildcard() {
+			p.errors = append(p.errors, fmt.Sprintf("%d:%d: Wildcard must be the last match arm",
+				p.curToken.Line, p.curToken.Column))
+			return nil
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		arm.Value = p.parseExpression(LOWEST)
+		expr.Arms = append(expr.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expr
+}
+
+// isMatchPattern reports whether pattern is a literal or the wildcard `_`
+func isMatchPattern(pattern ast.Expression) bool {
+	switch pattern := pattern.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.BooleanExpression, *ast.NullLiteral:
+		return true
+	case *ast.Identifier:
+		return pattern.Value == "_"
+	case *ast.PrefixExpression:
+		_, ok := pattern.Right.(*ast.IntegerLiteral)
+		return ok && pattern.Operator == "-"
+	}
+	return false
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{}
 	block.Token = p.curToken
 	block.Statements = []ast.Statement{}
 
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.EOF) {
+		p.errors = append(p.errors, fmt.Sprintf("%d:%d: Expected token %v, instead got %v",
+			p.curToken.Line, p.curToken.Column, token.RBRACE, token.EOF))
+	}
+
 	return block
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	params := []*ast.Identifier{}
+func (p *Parser) parseFunctionParameters(function *ast.FunctionLiteral) bool {
+	function.Parameters = []*ast.Identifier{}
+	function.Defaults = []ast.Expression{}
 
-	p.nextToken()
-
-	if p.curTokenIs(token.RPAREN) {
-		return params
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return true
 	}
 
-	ident := &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	}
-	params = append(params, ident)
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			function.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			// The rest parameter has to be the last one
+			break
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
 		ident := &ast.Identifier{
 			Token: p.curToken,
 			Value: p.curToken.Literal,
 		}
-		params = append(params, ident)
-	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		var defaultValue ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			defaultValue = p.parseExpression(LOWEST)
+			if defaultValue == nil {
+				return false
+			}
+		} else if n := len(function.Defaults); n > 0 && function.Defaults[n-1] != nil {
+			msg := fmt.Sprintf("%d:%d: Required parameter %v can't follow a parameter with a default value",
+				ident.Token.Line, ident.Token.Column, ident.Value)
+			p.errors = append(p.errors, msg)
+			return false
+		}
+
+		function.Parameters = append(function.Parameters, ident)
+		function.Defaults = append(function.Defaults, defaultValue)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	return params
+	return p.expectPeek(token.RPAREN)
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	function := &ast.FunctionLiteral{Token: p.curToken, Parameters: []*ast.Identifier{}}
 
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		function.Name = p.curToken.Literal
+	}
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	function.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(function) {
+		return nil
+	}
 
-	p.nextToken()
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
 
 	function.Body = p.parseBlockStatement()
 
 	return function
 }
 
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	macro := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	function := &ast.FunctionLiteral{}
+	if !p.parseFunctionParameters(function) {
+		return nil
+	}
+	if function.Rest != nil {
+		msg := fmt.Sprintf("%d:%d: Macros can't have rest parameters",
+			function.Rest.Token.Line, function.Rest.Token.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	for i, param := range function.Parameters {
+		if function.Defaults[i] != nil {
+			msg := fmt.Sprintf("%d:%d: Macros can't have default parameter values",
+				param.Token.Line, param.Token.Column)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+	macro.Parameters = function.Parameters
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	macro.Body = p.parseBlockStatement()
+
+	return macro
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	call := &ast.CallExpression{Token: p.curToken, Function: function}
 	call.Arguments = p.parseExpressionList(token.RPAREN)
 	return call
 }
 
+// parseMethodCall desugars `x.f(y)` into `f(x, y)`, so any function can
+// be called as if it were a method of its first argument
+func (p *Parser) parseMethodCall(receiver ast.Expression) ast.Expression {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	function := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	call := &ast.CallExpression{Token: p.curToken, Function: function}
+
+	args := p.parseExpressionList(token.RPAREN)
+	if args == nil {
+		return nil
+	}
+	call.Arguments = append([]ast.Expression{receiver}, args...)
+	return call
+}
+
+// parsePipeExpression desugars `x |> f` into `f(x)`. If the right-hand side
+// is already a call, x is passed as its first argument, so `x |> f(y)` is
+// `f(x, y)`
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+
+	p.nextToken()
+	right := p.parseExpression(PIPE)
+	if right == nil {
+		return nil
+	}
+
+	if call, ok := right.(*ast.CallExpression); ok {
+		args := make([]ast.Expression, 0, len(call.Arguments)+1)
+		args = append(args, left)
+		args = append(args, call.Arguments...)
+		return &ast.CallExpression{Token: call.Token, Function: call.Function, Arguments: args}
+	}
+
+	return &ast.CallExpression{Token: tok, Function: right, Arguments: []ast.Expression{left}}
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	args := []ast.Expression{}
 	p.nextToken()
@@ -327,8 +950,11 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 	args = append(args, p.parseExpression(LOWEST))
 
-	for p.peekTokenIs(",") {
+	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+		if p.peekTokenIs(end) {
+			break
+		}
 		p.nextToken()
 		args = append(args, p.parseExpression(LOWEST))
 	}
@@ -361,6 +987,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		val := p.parseExpression(LOWEST)
 		hl.Pairs[key] = val
+		hl.Keys = append(hl.Keys, key)
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -373,14 +1000,80 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hl
 }
 
+// parseIndexExpression parses either an index like a[i] or a slice like
+// a[low:high], where both bounds are optional
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	indexExp := &ast.IndexExpression{Left: left}
+	tok := p.curToken
 	p.nextToken()
-	indexExp.Index = p.parseExpression(LOWEST)
+
+	var low ast.Expression
+	if !p.curTokenIs(token.COLON) {
+		low = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: low}
+		}
+		p.nextToken()
+	}
+
+	sliceExp := &ast.SliceExpression{Token: tok, Left: left, Low: low}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		sliceExp.High = p.parseExpression(LOWEST)
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
-	return indexExp
+	return sliceExp
+}
+
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expr := &ast.AssignExpression{Token: p.curToken, Target: target}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		msg := fmt.Sprintf("%d:%d: Invalid assignment target %v",
+			p.curToken.Line, p.curToken.Column, target)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	p.nextToken()
+	// Parsing with a lower precedence makes assignment right-associative
+	expr.Value = p.parseExpression(ASSIGN - 1)
+	return expr
+}
+
+func (p *Parser) parsePostfixExpression(target ast.Expression) ast.Expression {
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		msg := fmt.Sprintf("%d:%d: Invalid %v target %v",
+			p.curToken.Line, p.curToken.Column, p.curToken.Literal, target)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return &ast.PostfixExpression{Token: p.curToken, Target: target, Operator: p.curToken.Literal}
+}
+
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	expr := &ast.TernaryExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	expr.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	// Parsing with a lower precedence makes nested ternaries right-associative
+	expr.Alternative = p.parseExpression(TERNARY - 1)
+	return expr
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -437,7 +1130,8 @@ func (p *Parser) curPrecedence() int {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	p.errors = append(p.errors, fmt.Sprintf("Expected token %v, instead got %v", t, p.peekToken.Type))
+	p.errors = append(p.errors, fmt.Sprintf("%d:%d: Expected token %v, instead got %v",
+		p.peekToken.Line, p.peekToken.Column, t, p.peekToken.Type))
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
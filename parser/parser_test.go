@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"monkey-interpreter/ast"
@@ -16,12 +17,14 @@ func TestReturnStatements(t *testing.T) {
 		{"return 5;", 5},
 		{"return x;", "x"},
 		{"return foo;", "foo"},
+		{"return 5", 5},
 	}
 
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
 		p := New(l)
 		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
 		if program == nil {
 			t.Fatalf("ParseProgram returned nil")
@@ -48,6 +51,35 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestBareReturnStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedString string
+	}{
+		{"return;", "return;"},
+		{"return", "return;"},
+		{"fn() { return }", "fn(){return;}"},
+		{"fn() { return; }", "fn(){return;}"},
+		{"fn(x) { if (x) { return; } x }", "fn(x){ifx return;x}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expectedString {
+			t.Errorf("Expected %q, instead got %q", tt.expectedString, program.String())
+		}
+	}
+
+	program := New(lexer.New("return;")).ParseProgram()
+	if returnStatement, ok := program.Statements[0].(*ast.ReturnStatement); !ok || returnStatement.ReturnValue != nil {
+		t.Errorf("Expected a ReturnStatement without a value, instead got %#v", program.Statements[0])
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input              string
@@ -57,6 +89,8 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 3;", "a", 3},
 		{"let b = false;", "b", false},
 		{"let foo = bar;", "foo", "bar"},
+		{"let x = 5", "x", 5},
+		{"let x = 5;", "x", 5},
 	}
 
 	for _, tt := range tests {
@@ -85,6 +119,90 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedNames []string
+		expected      string
+	}{
+		{"let [a, b, c] = [1, 2, 3];", []string{"a", "b", "c"}, "let [a, b, c] = [1, 2, 3];"},
+		{"let [x] = f()", []string{"x"}, "let [x] = f();"},
+		{"let [a, b,] = pair;", []string{"a", "b"}, "let [a, b] = pair;"},
+		{"let [] = empty;", nil, "let [] = empty;"},
+		{"let {name, age} = person;", []string{"name", "age"}, "let {name, age} = person;"},
+		{"let {a,} = h", []string{"a"}, "let {a} = h;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.DestructuringStatement)
+		if !ok {
+			t.Fatalf("Expected a DestructuringStatement, instead got %T", program.Statements[0])
+		}
+
+		if len(stmt.Names) != len(tt.expectedNames) {
+			t.Fatalf("Expected %d names, instead got %d", len(tt.expectedNames), len(stmt.Names))
+		}
+		for i, name := range tt.expectedNames {
+			testIdentifier(t, stmt.Names[i], name)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, 1] = b;", "1:9: Expected token IDENT, instead got INT"},
+		{"let [a b] = c;", "1:8: Expected token ,, instead got IDENT"},
+		{"let [a, b] c;", "1:12: Expected token =, instead got IDENT"},
+		{"let {a, \"b\"} = c;", "1:9: Expected token IDENT, instead got STRING"},
+		{"let {a] = c;", "1:7: Expected token ,, instead got ]"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("Expected error %q, instead got %v", tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestNextStatement(t *testing.T) {
+	p := New(lexer.New("let a = 1; let = 2; a + 1;"))
+
+	expected := []string{"let a = 1;", "(a + 1)"}
+	for _, want := range expected {
+		statement, ok := p.NextStatement()
+		if !ok {
+			t.Fatalf("Expected statement %q, instead got the end of input", want)
+		}
+		if statement.String() != want {
+			t.Errorf("Expected statement %q, instead got %q", want, statement.String())
+		}
+	}
+
+	if statement, ok := p.NextStatement(); ok {
+		t.Errorf("Expected the end of input, instead got %q", statement.String())
+	}
+	if len(p.Errors()) != 1 {
+		t.Errorf("Expected the skipped statement to be reported, instead got %v", p.Errors())
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	if len(p.Errors()) == 0 {
 		return
@@ -154,6 +272,77 @@ func TestParsingStrings(t *testing.T) {
 	}
 }
 
+func TestParsingInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"sum is ${1 + 2}"`, `"sum is ${(1 + 2)}"`},
+		{`"${a}${b}"`, `"${a}${b}"`},
+		{`"call ${f("x")}!"`, `"call ${f("x")}!"`},
+		{`"escaped \${a}"`, `"escaped \${a}"`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("Expected expression to be an InterpolatedString, instead got %T", stmt.Expression)
+		}
+		if str.String() != tt.expected {
+			t.Errorf("Expected %s, instead got %s", tt.expected, str.String())
+		}
+	}
+
+	l := lexer.New(`"a ${1 + 2} b"`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	str := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InterpolatedString)
+	if len(str.Parts) != 3 {
+		t.Fatalf("Expected 3 parts, instead got %d", len(str.Parts))
+	}
+	if lit, ok := str.Parts[0].(*ast.StringLiteral); !ok || lit.Value != "a " {
+		t.Errorf("Expected first part to be the literal %q, instead got %s", "a ", str.Parts[0])
+	}
+	testInfixExpression(t, str.Parts[1], 1, "+", 2)
+	if lit, ok := str.Parts[2].(*ast.StringLiteral); !ok || lit.Value != " b" {
+		t.Errorf("Expected last part to be the literal %q, instead got %s", " b", str.Parts[2])
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a ${1 + } b"`, "1:1: In string interpolation: 1:5: No prefix parse function found for EOF"},
+		{`"a ${} b"`, "1:1: String interpolation must contain a single expression"},
+		{`"a ${1; 2} b"`, "1:1: String interpolation must contain a single expression"},
+		{`"a ${1 + 2"`, "1:1: Unterminated ${ in string"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("Expected parser errors for %s", tt.input)
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("Expected error %q, instead got %q", tt.expected, p.Errors()[0])
+		}
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -212,6 +401,74 @@ func TestIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerDigitSeparators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1_000_000;", 1000000},
+		{"1_2_3;", 123},
+		{"0_1;", 1},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("Expected statement to be an expression statement, instead got %T", program.Statements[0])
+		}
+
+		intl, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("Expected expression to be an IntegerLiteral, instead got %T", stmt.Expression)
+		}
+		if intl.Value != tt.expected {
+			t.Errorf("Expected value to be %v, instead got %v", tt.expected, intl.Value)
+		}
+	}
+}
+
+func TestBigIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"99999999999999999999;", "99999999999999999999"},
+		{"18_446_744_073_709_551_616;", "18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.BigIntegers = true
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("Expected statement to be an expression statement, instead got %T", program.Statements[0])
+		}
+
+		lit, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+		if !ok {
+			t.Fatalf("Expected expression to be a BigIntegerLiteral, instead got %T", stmt.Expression)
+		}
+		if lit.Value.String() != tt.expected {
+			t.Errorf("Expected value to be %v, instead got %v", tt.expected, lit.Value)
+		}
+	}
+
+	// Literals that fit stay IntegerLiterals
+	p := New(lexer.New("5;"))
+	p.BigIntegers = true
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	testIntegerLiteral(t, program.Statements[0].(*ast.ExpressionStatement).Expression, 5)
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -243,6 +500,70 @@ func TestBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"2.5e-1", 0.25},
+		{"6.02E+23", 6.02e23},
+		{"1_000.5", 1000.5},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		lit, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Errorf("Expected expression to be a FloatLiteral, instead got %T", stmt.Expression)
+			continue
+		}
+		if lit.Value != tt.expected {
+			t.Errorf("Expected value %v, instead got %v", tt.expected, lit.Value)
+		}
+		if lit.String() != tt.input {
+			t.Errorf("Expected String() to be %q, instead got %q", tt.input, lit.String())
+		}
+	}
+}
+
+func TestNullLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null;", "null"},
+		{"!null;", "(!null)"},
+		{"null == null;", "(null == null)"},
+		{"let x = null;", "let x = null;"},
+		{"[1, null]", "[1, null]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, got)
+		}
+	}
+
+	program := New(lexer.New("null")).ParseProgram()
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if _, ok := stmt.Expression.(*ast.NullLiteral); !ok {
+		t.Fatalf("Expected expression to be a NullLiteral, instead got %T", stmt.Expression)
+	}
+}
+
 func TestPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input             string
@@ -251,6 +572,7 @@ func TestPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"+5;", "+", 5},
 		{"!false;", "!", false},
 	}
 
@@ -389,6 +711,55 @@ func TestParsingIndexExpression(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		hasLow   bool
+		hasHigh  bool
+		expected string
+	}{
+		{"a[1:3]", true, true, "a([1:3])"},
+		{"a[2:]", true, false, "a([2:])"},
+		{"a[:2]", false, true, "a([:2])"},
+		{"a[:]", false, false, "a([:])"},
+		{"a[-2:-1]", true, true, "a([(-2):(-1)])"},
+		{"a[i + 1:len(a)]", true, true, "a([(i + 1):len(a)])"},
+		{"a[x ? 1 : 2:]", true, false, "a([(x ? 1 : 2):])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		sliceExp, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Errorf("Expected a SliceExpression for %q, instead got %T", tt.input, stmt.Expression)
+			continue
+		}
+
+		testIdentifier(t, sliceExp.Left, "a")
+		if (sliceExp.Low != nil) != tt.hasLow || (sliceExp.High != nil) != tt.hasHigh {
+			t.Errorf("Expected bounds %v and %v in %q, instead got %v and %v",
+				tt.hasLow, tt.hasHigh, tt.input, sliceExp.Low, sliceExp.High)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("a[x ? 1 : 2]"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if _, ok := stmt.Expression.(*ast.IndexExpression); !ok {
+		t.Errorf("Expected a ternary index to stay an IndexExpression, instead got %T", stmt.Expression)
+	}
+}
+
 func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
 	ident, ok := exp.(*ast.Identifier)
 	if !ok {
@@ -481,6 +852,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"+a * b",
+			"((+a) * b)",
+		},
+		{
+			"a + +b",
+			"(a + (+b))",
+		},
 		{
 			"!-a",
 			"(!(-a))",
@@ -557,41 +936,197 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
-	}
-	for _, tt := range tests {
-		l := lexer.New(tt.input)
-		p := New(l)
-		program := p.ParseProgram()
-		checkParserErrors(t, p)
-
-		actual := program.String()
-		if actual != tt.expected {
-			t.Errorf("expected=%q, got=%q", tt.expected, actual)
-		}
-	}
-}
-
-func TestIfExpression(t *testing.T) {
-	input := `if (x < y) { x }`
-
-	l := lexer.New(input)
-	p := New(l)
-
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
-
-	if len(program.Statements) != 1 {
-		t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
-	}
-
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-
-	if !ok {
-		t.Fatalf("Expected statement to be an ExpressionStatement, instead got %T", program.Statements[0])
-	}
-
-	expr, ok := stmt.Expression.(*ast.IfExpression)
-
+		{
+			"a[0] = 1 + 2",
+			"(a([0]) = (1 + 2))",
+		},
+		{
+			"a[0] = b[1] = c",
+			"(a([0]) = (b([1]) = c))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a ** b * c",
+			"((a ** b) * c)",
+		},
+		{
+			"-a ** b",
+			"((-a) ** b)",
+		},
+		{
+			"a ? b : c",
+			"(a ? b : c)",
+		},
+		{
+			"a == 1 ? b + 1 : c * 2",
+			"((a == 1) ? (b + 1) : (c * 2))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"x = a < b ? a : b",
+			"(x = ((a < b) ? a : b))",
+		},
+		{
+			"f(a ? b : c, d)",
+			"f((a ? b : c), d)",
+		},
+		{
+			"1 < x < 10",
+			"(1 < x < 10)",
+		},
+		{
+			"i++",
+			"(i++)",
+		},
+		{
+			"-i-- * 2",
+			"((-(i--)) * 2)",
+		},
+		{
+			"a[0]++ + b[1]",
+			"((a([0])++) + b([1]))",
+		},
+		{
+			"5--3",
+			"(5 - (-3))",
+		},
+		{
+			"a * b--c",
+			"((a * b) - (-c))",
+		},
+		{
+			"x-- - 1",
+			"((x--) - 1)",
+		},
+		{
+			"a < b > c < d",
+			"(a < b > c < d)",
+		},
+		{
+			"(a < b) < c",
+			"((a < b) < c)",
+		},
+		{
+			"a + 1 < b * 2 < c == true",
+			"(((a + 1) < (b * 2) < c) == true)",
+		},
+		{
+			"x |> f",
+			"f(x)",
+		},
+		{
+			"x |> f |> g",
+			"g(f(x))",
+		},
+		{
+			"x |> f(a, b)",
+			"f(x, a, b)",
+		},
+		{
+			"a + b * c |> f",
+			"f((a + (b * c)))",
+		},
+		{
+			"x |> f == y",
+			"(f(x) == y)",
+		},
+		{
+			"x |> f < y |> g",
+			"(f(x) < g(y))",
+		},
+		{
+			"y = x |> f",
+			"(y = f(x))",
+		},
+		{
+			"c ? a : b |> f",
+			"(c ? a : f(b))",
+		},
+		{
+			"x |> f(y)[0]",
+			"f(y)([0])(x)",
+		},
+		{
+			"x.f()",
+			"f(x)",
+		},
+		{
+			"x.f(a, b + c)",
+			"f(x, a, (b + c))",
+		},
+		{
+			"a + x.f() * 2",
+			"(a + (f(x) * 2))",
+		},
+		{
+			"-x.f()",
+			"(-f(x))",
+		},
+		{
+			"x.f().g(y)",
+			"g(f(x), y)",
+		},
+		{
+			"a[0].f()[1]",
+			"f(a([0]))([1])",
+		},
+		{
+			"[1, 2].f()",
+			"f([1, 2])",
+		},
+		{
+			"x |> f.g()",
+			"g(x, f)",
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	input := `if (x < y) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+
+	if !ok {
+		t.Fatalf("Expected statement to be an ExpressionStatement, instead got %T", program.Statements[0])
+	}
+
+	expr, ok := stmt.Expression.(*ast.IfExpression)
+
 	if !ok {
 		t.Fatalf("Expected expression to be an IfExpression, instead got %T", stmt.Expression)
 	}
@@ -678,6 +1213,60 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { z }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative does not contain 1 statement. got=%v", exp.Alternative)
+	}
+
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+			exp.Alternative.Statements[0])
+	}
+
+	nested, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+
+	if !testInfixExpression(t, nested.Condition, "x", ">", "y") {
+		return
+	}
+
+	if nested.Alternative == nil || len(nested.Alternative.Statements) != 1 {
+		t.Fatalf("nested.Alternative does not contain 1 statement. got=%v", nested.Alternative)
+	}
+
+	last := nested.Alternative.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, last.Expression, "z")
+
+	if got := program.String(); got != "if(x < y) xelse if(x > y) yelse z" {
+		t.Errorf("program.String() wrong. got=%q", got)
+	}
+}
+
 func TestFunctionLiteral(t *testing.T) {
 	input := `fn(a, b) { a + b }`
 
@@ -950,3 +1539,529 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	input := "1 + 2 = 3"
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("Expected a parser error for an invalid assignment target")
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "1:7: Expected token =, instead got INT"},
+		{"let a = 1;\n  let = 2;", "2:7: Expected token IDENT, instead got ="},
+		{"let a = 1;\n\n)", "3:1: No prefix parse function found for )"},
+		{"let a = 1__000;", "1:9: Illegal token 1__000"},
+		{"let a = 1 + 10_;", "1:13: Illegal token 10_"},
+		{"let a = 1;\nlet b = [1, 99999999999999999999];", "2:13: could not parse 99999999999999999999 as integer"},
+		{"99_999_999_999_999_999_999", "1:1: could not parse 99_999_999_999_999_999_999 as integer"},
+		{"let x = 1e;", "1:9: could not parse 1e as float"},
+		{"2.5e-", "1:1: could not parse 2.5e- as float"},
+		{"1e400", "1:1: could not parse 1e400 as float"},
+		{"x.len", "1:6: Expected token (, instead got EOF"},
+		{"x.1()", "1:3: Expected token IDENT, instead got INT"},
+		{"fn(x) {\n  x", "2:4: Expected token }, instead got EOF"},
+		{"if (x) { 1 } else {", "1:20: Expected token }, instead got EOF"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("Expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("Expected error %q, instead got %q", tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements int
+	}{
+		{
+			"let x 5; let = 10; let y = 3;",
+			[]string{
+				"1:7: Expected token =, instead got INT",
+				"1:14: Expected token IDENT, instead got =",
+			},
+			1,
+		},
+		{
+			"let a = 1;\n(2 + ;\nlet b = ;\na + 1",
+			[]string{
+				"2:6: No prefix parse function found for ;",
+				"3:1: Expected token ), instead got LET",
+				"3:9: No prefix parse function found for ;",
+			},
+			2,
+		},
+		{
+			"let f = fn() { let = 1; 2 };\nf()",
+			[]string{
+				"1:20: Expected token IDENT, instead got =",
+			},
+			2,
+		},
+		{
+			"let f = fn(a = 1, b){ a }",
+			[]string{
+				"1:19: Required parameter b can't follow a parameter with a default value",
+			},
+			0,
+		},
+		{
+			"let f = fn(a = 1, b){ if (a) { a } };\nlet g = 2",
+			[]string{
+				"1:19: Required parameter b can't follow a parameter with a default value",
+			},
+			1,
+		},
+		{
+			"if (x) { let = 1; 2 }; 3",
+			[]string{
+				"1:14: Expected token IDENT, instead got =",
+			},
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if !reflect.DeepEqual(p.Errors(), tt.expectedErrors) {
+			t.Errorf("Expected errors %q, instead got %q", tt.expectedErrors, p.Errors())
+		}
+
+		if len(program.Statements) != tt.expectedStatements {
+			t.Fatalf("Expected %v statements, instead got %v", tt.expectedStatements, len(program.Statements))
+		}
+
+		for _, statement := range program.Statements {
+			if statement == nil {
+				t.Fatalf("Program contains a nil statement")
+			}
+		}
+		// String must not panic on a partially invalid program
+		_ = program.String()
+	}
+}
+
+func TestOptionalSemicolons(t *testing.T) {
+	input := `let x = 5
+let y = x
+return x + y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "let x = 5;let y = x;return (x + y);" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"for (let i = 0; i < 10; i = i + 1) { x }",
+			"for (let i = 0; (i < 10); (i = (i + 1))) x",
+		},
+		{
+			"for (i = 0; i < 10;) { x; y }",
+			"for ((i = 0); (i < 10); ) xy",
+		},
+		{
+			"for (;;) { }",
+			"for (; ; ) ",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("Expected a ForStatement, instead got %T", program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestDoExpression(t *testing.T) {
+	input := "let x = do { let y = 2; y * 3 };"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("Expected a LetStatement, instead got %T", program.Statements[0])
+	}
+
+	do, ok := stmt.Value.(*ast.DoExpression)
+	if !ok {
+		t.Fatalf("Expected a DoExpression, instead got %T", stmt.Value)
+	}
+
+	if len(do.Body.Statements) != 2 {
+		t.Fatalf("Expected body to have 2 statements, instead got %v", len(do.Body.Statements))
+	}
+
+	if stmt.String() != "let x = do let y = 2;(y * 3);" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+
+	p = New(lexer.New("do 1"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "1:4: Expected token {, instead got INT" {
+		t.Errorf("Expected an error for a do without a block, got %v", p.Errors())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match x { 1 => "one", -2 => "minus two", "a" => 3, true => y + 1, null => 0, _ => z, }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	match, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("Expected a MatchExpression, instead got %T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, match.Subject, "x") {
+		return
+	}
+
+	if len(match.Arms) != 6 {
+		t.Fatalf("Expected 6 arms, instead got %v", len(match.Arms))
+	}
+
+	if !match.Arms[5].IsWildcard() || match.Arms[0].IsWildcard() {
+		t.Errorf("Only the last arm should be a wildcard")
+	}
+
+	expected := `match x { 1 => "one", (-2) => "minus two", "a" => 3, true => (y + 1), null => 0, _ => z }`
+	if match.String() != expected {
+		t.Errorf("match.String() wrong. got=%q", match.String())
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { y => 1 }", "1:11: Invalid match pattern y"},
+		{"match x { 1 + 1 => 1 }", "1:15: Invalid match pattern (1 + 1)"},
+		{"match x { _ => 1, 2 => 2 }", "1:19: Wildcard must be the last match arm"},
+		{"match x { 1 2 }", "1:13: Expected token =>, instead got INT"},
+		{"match x { 1 => 1 2 => 2 }", "1:18: Expected token ,, instead got INT"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("Expected parser errors for %q", tt.input)
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("Expected error %q, instead got %q", tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := "while (x < 10) { if (x == 5) { break; } continue }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected program to have 1 statement, instead got %v", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("Expected a WhileStatement, instead got %T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("Expected body to have 2 statements, instead got %v", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("Expected a ContinueStatement, instead got %T", stmt.Body.Statements[1])
+	}
+
+	if stmt.String() != "while(x < 10) if(x == 5) break;continue;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestNamedFunctionLiteral(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+		expected     string
+	}{
+		{"fn add(a, b) { a + b }", "add", "fn add(a, b){(a + b)}"},
+		{"fn(a, b) { a + b }", "", "fn(a, b){(a + b)}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("Expected a FunctionLiteral, instead got %T", stmt.Expression)
+		}
+
+		if function.Name != tt.expectedName {
+			t.Errorf("Expected function name %q, instead got %q", tt.expectedName, function.Name)
+		}
+
+		if function.String() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, function.String())
+		}
+	}
+}
+
+func TestFunctionDefaultParameters(t *testing.T) {
+	input := "fn(x, y = 10, z = a + b) { x }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function := stmt.Expression.(*ast.FunctionLiteral)
+
+	if len(function.Parameters) != 3 || len(function.Defaults) != 3 {
+		t.Fatalf("Expected 3 parameters and defaults, instead got %v and %v",
+			len(function.Parameters), len(function.Defaults))
+	}
+
+	if function.Defaults[0] != nil {
+		t.Errorf("Expected no default for x, instead got %v", function.Defaults[0])
+	}
+	testIntegerLiteral(t, function.Defaults[1], 10)
+	testInfixExpression(t, function.Defaults[2], "a", "+", "b")
+
+	if function.String() != "fn(x, y = 10, z = (a + b)){x}" {
+		t.Errorf("function.String() wrong. got=%q", function.String())
+	}
+
+	l = lexer.New("fn(x = 1, y) { x }")
+	p = New(l)
+	p.ParseProgram()
+
+	expected := "1:11: Required parameter y can't follow a parameter with a default value"
+	if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+		t.Errorf("Expected error %q, instead got %q", expected, p.Errors())
+	}
+}
+
+func TestFunctionRestParameter(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		hasError bool
+	}{
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest){rest}", false},
+		{"fn(...args) { args }", "fn(...args){args}", false},
+		{"fn(a = 1, ...rest) { a }", "fn(a = 1, ...rest){a}", false},
+		{"fn(...rest, last) { rest }", "", true},
+		{"fn(...) { 1 }", "", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if tt.hasError {
+			if len(p.Errors()) == 0 {
+				t.Errorf("Expected parser errors for %q", tt.input)
+			}
+			continue
+		}
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if function.Rest == nil {
+			t.Fatalf("Expected function to have a rest parameter")
+		}
+
+		if function.String() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, function.String())
+		}
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3,]", "[1, 2, 3]"},
+		{"[1,]", "[1]"},
+		{"add(1, 2,)", "add(1, 2)"},
+		{"add(\n\t1,\n\t2,\n)", "add(1, 2)"},
+		{`{"a": 1,}`, "{\n\"a\":1\n}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("Expected %q, instead got %q", tt.expected, program.String())
+		}
+	}
+
+	invalid := []string{"[,]", "add(1,,)", `{"a": 1,,}`}
+	for _, input := range invalid {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("Expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+			len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+			len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestInvalidPostfixTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5++", "1:2: Invalid ++ target 5"},
+		{"f()--", "1:4: Invalid -- target f()"},
+		{"(a + b)++", "1:8: Invalid ++ target (a + b)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("Expected parser errors for %q", tt.input)
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("Expected error %q, instead got %q", tt.expected, p.Errors()[0])
+		}
+	}
+}
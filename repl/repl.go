@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
+	"monkey-interpreter/ast"
 	"monkey-interpreter/evaluator"
 	"monkey-interpreter/lexer"
 	"monkey-interpreter/object"
@@ -13,27 +15,66 @@ import (
 
 const PROMPT = ">>"
 
+// CONTINUATION_PROMPT is shown while an incomplete input, like a function
+// whose closing brace hasn't been typed yet, is being continued
+const CONTINUATION_PROMPT = ".."
+
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+	pending := ""
 
 	for {
-		fmt.Print(PROMPT)
+		if pending == "" {
+			fmt.Fprint(out, PROMPT)
+		} else {
+			fmt.Fprint(out, CONTINUATION_PROMPT)
+		}
 		scanned := scanner.Scan()
 		if !scanned {
+			io.WriteString(out, "\n")
 			return
 		}
 		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if pending == "" {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if strings.HasPrefix(strings.TrimSpace(line), ":") {
+				if quit := runCommand(out, strings.TrimSpace(line), env, macroEnv); quit {
+					return
+				}
+				continue
+			}
+		}
+
+		input := line
+		if pending != "" {
+			input = pending + "\n" + line
+		}
+
+		// Keep reading while the input ends too early, until a blank line
+		// gives up on it and reports the errors
+		program, errors := parse(input)
+		if len(errors) != 0 && isIncomplete(errors) && strings.TrimSpace(line) != "" {
+			pending = input
+			continue
+		}
+		pending = ""
+
+		if len(errors) != 0 {
+			printParserErrors(out, errors)
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		evaluated, err := evalProgram(program, env, macroEnv)
+		if err != nil {
+			printParserErrors(out, []string{err.Error()})
+			continue
+		}
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -41,7 +82,95 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
+func parse(input string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(input))
+	p.BigIntegers = evaluator.BigIntegers
+	program := p.ParseProgram()
+	return program, p.Errors()
+}
+
+// isIncomplete reports whether the parser ran out of input, which is the
+// case when a bracket is left open or an expression is cut short. Only the
+// first error counts, since the ones after a real mistake often run into
+// the end of the input as well
+func isIncomplete(errors []string) bool {
+	return strings.HasSuffix(errors[0], "instead got EOF") || strings.HasSuffix(errors[0], "found for EOF")
+}
+
+// evalProgram returns an error if a macro fails to expand
+func evalProgram(program *ast.Program, env, macroEnv *object.Environment) (object.Object, error) {
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluator.Eval(expanded, env), nil
+}
+
+// evalLine evaluates a line of input in env, printing any parser errors.
+// It returns nil if the line didn't parse
+func evalLine(out io.Writer, line string, env, macroEnv *object.Environment) object.Object {
+	program, errors := parse(line)
+	if len(errors) != 0 {
+		printParserErrors(out, errors)
+		return nil
+	}
+
+	evaluated, err := evalProgram(program, env, macroEnv)
+	if err != nil {
+		printParserErrors(out, []string{err.Error()})
+		return nil
+	}
+	return evaluated
+}
+
+// runCommand runs a line starting with a colon, like `:env` or
+// `:type x + 1`, and reports whether the REPL should exit
+func runCommand(out io.Writer, line string, env, macroEnv *object.Environment) bool {
+	name, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i != -1 {
+		name, arg = line[:i], strings.TrimSpace(line[i:])
+	}
+
+	switch name {
+	case ":quit":
+		return true
+
+	case ":env":
+		for _, key := range env.Keys() {
+			val, _ := env.Get(key)
+			fmt.Fprintf(out, "%s = %s\n", key, val.Inspect())
+		}
+
+	case ":type":
+		if arg == "" {
+			io.WriteString(out, "usage: :type <expr>\n")
+			break
+		}
+		// Neither bindings nor macros defined by the expression are kept
+		snapshot, macroSnapshot := env.Snapshot(), macroEnv.Snapshot()
+		evaluated := evalLine(out, arg, env, macroEnv)
+		env.Restore(snapshot)
+		macroEnv.Restore(macroSnapshot)
+
+		if evaluated != nil {
+			io.WriteString(out, string(evaluated.Type()))
+			io.WriteString(out, "\n")
+		}
+
+	case ":reset":
+		env.Restore(object.NewEnvironment())
+		macroEnv.Restore(object.NewEnvironment())
+
+	default:
+		fmt.Fprintf(out, "unknown command %s, available commands are :quit, :env, :type <expr> and :reset\n", name)
+	}
+	return false
+}
+
 func printParserErrors(out io.Writer, errors []string) {
+	io.WriteString(out, "parser errors:\n")
 	for _, msg := range errors {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
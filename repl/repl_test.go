@@ -0,0 +1,146 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let a = 5;\na * 2\n",
+			">>5\n>>10\n>>\n",
+		},
+		{
+			"let add = fn(x, y) { x + y };\n\nadd(1, 2)\n",
+			">>fn add(x, y){\n(x + y)\n}\n>>>>3\n>>\n",
+		},
+		{
+			"   \n\"foo\"",
+			">>>>\"foo\"\n>>\n",
+		},
+		{
+			"(1\n\n1 + 1\n",
+			">>..parser errors:\n\t2:1: Expected token ), instead got EOF\n>>2\n>>\n",
+		},
+		{
+			"foobar\n",
+			">>ERROR: 1:1: identifier not found: foobar\n>>\n",
+		},
+		{
+			"",
+			">>\n",
+		},
+		{
+			"let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };\nunless(1 > 2, 10, 20)\n",
+			">>>>10\n>>\n",
+		},
+		{
+			"let m = macro(x) { 1 };\nm(2)\n1\n:type m(2)\n",
+			">>>>parser errors:\n\t1:2: macro m must return a quote, got INTEGER\n>>1\n>>parser errors:\n\t1:2: macro m must return a quote, got INTEGER\n>>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out := bytes.Buffer{}
+		Start(strings.NewReader(tt.input), &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("Expected output %q, instead got %q", tt.expected, out.String())
+		}
+	}
+}
+
+func TestMultilineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let add = fn(x, y) {\n  x + y\n};\nadd(1, 2)\n",
+			">>....fn add(x, y){\n(x + y)\n}\n>>3\n>>\n",
+		},
+		{
+			"let a = [1,\n2,\n3]; len(a)\n",
+			">>....3\n>>\n",
+		},
+		{
+			"1 +\n\n",
+			">>..parser errors:\n\t2:1: No prefix parse function found for EOF\n>>\n",
+		},
+		{
+			"if (true) {\n  1 +\n  )\n}\n",
+			">>....parser errors:\n\t3:3: No prefix parse function found for )\n\t3:5: Expected token }, instead got EOF\n>>parser errors:\n\t1:1: No prefix parse function found for }\n>>\n",
+		},
+		{
+			"fn(x) {\n:quit\n}\n",
+			">>..parser errors:\n\t2:1: No prefix parse function found for :\n\t2:7: Expected token }, instead got EOF\n>>parser errors:\n\t1:1: No prefix parse function found for }\n>>\n",
+		},
+		{
+			"let a = fn() {\n",
+			">>..\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out := bytes.Buffer{}
+		Start(strings.NewReader(tt.input), &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("Expected output %q, instead got %q", tt.expected, out.String())
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			":quit\n1 + 1\n",
+			">>",
+		},
+		{
+			"  :quit  \n",
+			">>",
+		},
+		{
+			"let b = 2;\nlet a = [1];\n:env\n",
+			">>2\n>>[1]\n>>a = [1]\nb = 2\n>>\n",
+		},
+		{
+			":type 1 + 1\n:type \"a\"\n:type fn(x) { x }\n:type missing\n",
+			">>INTEGER\n>>STRING\n>>FUNCTION\n>>ERROR\n>>\n",
+		},
+		{
+			"let a = 1;\n:type let a = true; a\na\n:type let b = 2;\nb\n",
+			">>1\n>>BOOLEAN\n>>1\n>>INTEGER\n>>ERROR: 1:1: identifier not found: b\n>>\n",
+		},
+		{
+			":type (1\n:type\n",
+			">>parser errors:\n\t1:3: Expected token ), instead got EOF\n>>usage: :type <expr>\n>>\n",
+		},
+		{
+			"let a = 1;\nlet m = macro() { quote(2) };\n:reset\n:env\na\nm()\n",
+			">>1\n>>>>>>>>ERROR: 1:1: identifier not found: a\n>>ERROR: 1:1: identifier not found: m\n>>\n",
+		},
+		{
+			":help\n",
+			">>unknown command :help, available commands are :quit, :env, :type <expr> and :reset\n>>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out := bytes.Buffer{}
+		Start(strings.NewReader(tt.input), &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("Expected output %q, instead got %q", tt.expected, out.String())
+		}
+	}
+}